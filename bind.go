@@ -0,0 +1,361 @@
+package encoding
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/things-go/encoding/codec"
+	"github.com/things-go/encoding/form"
+)
+
+// The struct tags select the request source of a field for BindAll,
+// the same as the codec.FieldError's Source.
+const (
+	TagPath   = codec.SourcePath
+	TagQuery  = codec.SourceQuery
+	TagHeader = codec.SourceHeader
+	TagCookie = codec.SourceCookie
+)
+
+// sourceCodecs decode the source values keyed by the struct tag of the source.
+var sourceCodecs = map[string]*form.Codec{
+	TagPath:   form.New(TagPath),
+	TagQuery:  form.New(TagQuery),
+	TagHeader: form.New(TagHeader),
+	TagCookie: form.New(TagCookie),
+}
+
+// sourceField is a struct field tagged with a request source.
+type sourceField struct {
+	source string
+	name   string
+}
+
+// sourceFieldsCache caches the []sourceField of struct types.
+var sourceFieldsCache sync.Map // map[reflect.Type][]sourceField
+
+// sourceFields returns the fields tagged with a request source of the struct type,
+// the fields of embedded structs are included.
+func sourceFields(t reflect.Type) []sourceField {
+	if fields, ok := sourceFieldsCache.Load(t); ok {
+		return fields.([]sourceField)
+	}
+	fields := appendSourceFields(nil, t, map[reflect.Type]bool{})
+	sourceFieldsCache.Store(t, fields)
+	return fields
+}
+
+// appendSourceFields appends the tagged fields of the struct type, visited guards
+// against the struct types which embed themselves, e.g. `type Node struct{ *Node }`.
+func appendSourceFields(fields []sourceField, t reflect.Type, visited map[reflect.Type]bool) []sourceField {
+	if visited[t] {
+		return fields
+	}
+	visited[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = appendSourceFields(fields, ft, visited)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		for _, source := range []string{TagPath, TagQuery, TagHeader, TagCookie} {
+			name, _, _ := strings.Cut(sf.Tag.Get(source), ",")
+			if name != "" && name != "-" {
+				fields = append(fields, sourceField{source: source, name: name})
+			}
+		}
+	}
+	return fields
+}
+
+// sourceValues returns the values of the tagged fields for each request source.
+func sourceValues(req *http.Request, pathVars url.Values, fields []sourceField) map[string]url.Values {
+	values := make(map[string]url.Values, len(sourceCodecs))
+	var query url.Values
+	var cookies []*http.Cookie
+	for _, f := range fields {
+		var vs []string
+		switch f.source {
+		case TagPath:
+			vs = pathVars[f.name]
+		case TagQuery:
+			if query == nil {
+				query = req.URL.Query()
+			}
+			vs = query[f.name]
+		case TagHeader:
+			vs = req.Header.Values(f.name)
+		case TagCookie:
+			if cookies == nil {
+				cookies = req.Cookies()
+			}
+			for _, c := range cookies {
+				if c.Name == f.name {
+					vs = append(vs, c.Value)
+				}
+			}
+		}
+		if len(vs) == 0 {
+			continue
+		}
+		if values[f.source] == nil {
+			values[f.source] = make(url.Values)
+		}
+		values[f.source][f.name] = vs
+	}
+	return values
+}
+
+// isSafeMethod reports whether the method is safe, see RFC 9110 section 9.2.1,
+// the request of a safe method is bound from the query only.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// hasBody reports whether the request has a body to bind,
+// it is false for a safe method, a missing body or `Content-Length: 0`.
+// If the length is unknown, it peeks the first byte of the body.
+// A multipart form already parsed is considered as the body.
+func hasBody(req *http.Request) (bool, error) {
+	if isSafeMethod(req.Method) {
+		return false, nil
+	}
+	if req.MultipartForm != nil {
+		return true, nil
+	}
+	if req.Body == nil || req.Body == http.NoBody || req.ContentLength == 0 {
+		return false, nil
+	}
+	if req.ContentLength > 0 {
+		return true, nil
+	}
+	var b [1]byte
+	n, err := io.ReadFull(req.Body, b[:])
+	if n == 0 {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, err
+	}
+	req.Body = &peekedBody{Reader: io.MultiReader(bytes.NewReader(b[:n]), req.Body), body: req.Body}
+	return true, nil
+}
+
+// peekedBody is the request body with the peeked bytes put back.
+type peekedBody struct {
+	io.Reader
+	body io.ReadCloser
+}
+
+func (b *peekedBody) Close() error { return b.body.Close() }
+
+// BindAll binds the passed struct pointer or proto message from every source of the request,
+// the path variables, the query, the headers, the cookies and the body.
+//
+// For a struct, the fields are bound in the precedence, a later one overwrites an earlier one:
+//
+//  1. the query, see BindQuery.
+//  2. the body, see Bind, skipped for a safe method or an empty body.
+//  3. the query, header and cookie of the fields tagged with `query:"page"`, `header:"X-Request-Id"`
+//     and `cookie:"session"`, in that order.
+//  4. the path variables, see BindUri.
+//  5. the path variables of the fields tagged with `path:"id"`.
+//
+// Only the top level fields (the fields of embedded structs included) are looked up for source tags.
+//
+// For a proto message, it follows the google.api.http semantics, see BindProto,
+// the body selector is "*" if the request has a body, otherwise it is "".
+func (r *Encoding) BindAll(req *http.Request, pathVars url.Values, v any) error {
+	reg := r.load()
+	withBody, err := hasBody(req)
+	if err != nil {
+		return err
+	}
+	if m, ok := v.(proto.Message); ok {
+		body := ""
+		if withBody {
+			body = "*"
+		}
+		return reg.bindProto(req, pathVars, m, body)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("encoding: BindAll requires a non-nil pointer, got %T", v)
+	}
+	if err := reg.bindQuery(req.URL.Query(), v); err != nil {
+		return err
+	}
+	if withBody {
+		if err := reg.bindBody(req, v); err != nil {
+			return err
+		}
+	}
+	var values map[string]url.Values
+	if t := rv.Type().Elem(); t.Kind() == reflect.Struct {
+		values = sourceValues(req, pathVars, sourceFields(t))
+	}
+	for _, source := range []string{TagQuery, TagHeader, TagCookie} {
+		if vs := values[source]; len(vs) > 0 {
+			if err := codec.SetSource(sourceCodecs[source].Decode(vs, v), source); err != nil {
+				return err
+			}
+		}
+	}
+	if len(pathVars) > 0 {
+		if err := reg.bindPath(pathVars, v); err != nil {
+			return err
+		}
+	}
+	if vs := values[TagPath]; len(vs) > 0 {
+		return codec.SetSource(sourceCodecs[TagPath].Decode(vs, v), codec.SourcePath)
+	}
+	return nil
+}
+
+// BindProto binds the proto message from the request following the google.api.http semantics,
+// the body selector decides which fields come from the body:
+//
+//   - "*": the body is bound to the message, the query is ignored.
+//   - "": the request has no body, the query is bound to the message.
+//   - "field.path": the body is bound to the message field, the query is bound to the
+//     other fields, the field must be a message.
+//
+// The path variables are bound last, so they always take precedence.
+func (r *Encoding) BindProto(req *http.Request, pathVars url.Values, m proto.Message, body string) error {
+	return r.load().bindProto(req, pathVars, m, body)
+}
+
+func (r *registry) bindProto(req *http.Request, pathVars url.Values, m proto.Message, body string) error {
+	switch body {
+	case "*":
+		if err := r.bindBody(req, m); err != nil {
+			return err
+		}
+	case "":
+		if err := r.bindQuery(req.URL.Query(), m); err != nil {
+			return err
+		}
+	default:
+		field, err := protoBodyField(m.ProtoReflect(), body)
+		if err != nil {
+			return err
+		}
+		if err = r.bindBody(req, field.Interface()); err != nil {
+			return err
+		}
+		query := req.URL.Query()
+		for k := range query {
+			if k == body || strings.HasPrefix(k, body+".") {
+				delete(query, k)
+			}
+		}
+		if err = r.bindQuery(query, m); err != nil {
+			return err
+		}
+	}
+	if len(pathVars) > 0 {
+		return r.bindPath(pathVars, m)
+	}
+	return nil
+}
+
+// protoBodyField returns the mutable message field selected by the body selector,
+// the field path is dot separated proto names or JSON names.
+func protoBodyField(m protoreflect.Message, body string) (protoreflect.Message, error) {
+	for _, name := range strings.Split(body, ".") {
+		fields := m.Descriptor().Fields()
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = fields.ByJSONName(name)
+		}
+		if fd == nil {
+			return nil, fmt.Errorf("encoding: body field %q not found in %s", body, m.Descriptor().FullName())
+		}
+		if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+			return nil, fmt.Errorf("encoding: body field %q should be a message", body)
+		}
+		m = m.Mutable(fd).Message()
+	}
+	return m, nil
+}
+
+// bindQuery binds the query, the failures are reported with codec.SourceQuery.
+func (r *registry) bindQuery(query url.Values, v any) error {
+	return codec.SetSource(r.mimeQuery.Decode(query, v), codec.SourceQuery)
+}
+
+// bindPath binds the path variables, the failures are reported with codec.SourcePath.
+func (r *registry) bindPath(pathVars url.Values, v any) error {
+	return codec.SetSource(r.mimeUri.Decode(pathVars, v), codec.SourcePath)
+}
+
+// bindBody binds the request body, see Encoding.Bind,
+// the failures are reported with codec.SourceBody.
+func (r *registry) bindBody(req *http.Request, v any) error {
+	return codec.SetSource(r.decodeBody(req, v), codec.SourceBody)
+}
+
+func (r *registry) decodeBody(req *http.Request, v any) error {
+	contentType, marshaller, err := r.negotiateInbound(req)
+	if err != nil {
+		return err
+	}
+	if err = r.decompressBody(req); err != nil {
+		return err
+	}
+	limits := r.limitsFor(contentType)
+	body := limits.limitBody(req)
+	switch contentType {
+	case MIMEMultipartPOSTForm:
+		m, ok := marshaller.(codec.FormCodec)
+		if !ok {
+			return fmt.Errorf("encoding: not supported marshaller(%v)", contentType)
+		}
+		if err := limits.parseMultipartForm(req); err != nil {
+			return body.check(err)
+		}
+		if err := m.Decode(req.MultipartForm.Value, v); err != nil {
+			return err
+		}
+		return bindFiles(req.MultipartForm.File, v, multipartTagName(marshaller))
+	case MIMEPOSTForm:
+		if m, ok := marshaller.(codec.FormCodec); ok && limits.MaxFormKeys > 0 {
+			b, err := io.ReadAll(req.Body)
+			if err != nil {
+				return body.check(err)
+			}
+			if err = limits.checkFormKeys(bytes.Count(b, []byte("&")) + 1); err != nil {
+				return err
+			}
+			values, err := url.ParseQuery(string(b))
+			if err != nil {
+				return err
+			}
+			return m.Decode(values, v)
+		}
+	}
+	return body.check(marshaller.NewDecoder(req.Body).Decode(v))
+}
@@ -0,0 +1,276 @@
+package encoding
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/things-go/encoding/codec"
+	"github.com/things-go/encoding/jsonpb"
+	"github.com/things-go/encoding/testdata/examplepb"
+)
+
+type BindAllMeta struct {
+	RequestId string `json:"requestId" header:"X-Request-Id"`
+}
+
+type BindAllMode struct {
+	BindAllMeta
+	Id      string   `json:"id" path:"id"`
+	Name    string   `json:"name"`
+	Page    int      `json:"page" query:"p"`
+	Session string   `json:"session" cookie:"session"`
+	Tags    []string `json:"tags" header:"X-Tag"`
+}
+
+func Test_Encoding_BindAll(t *testing.T) {
+	registry := New()
+	newRequest := func(method, target, body string) *http.Request {
+		var r *http.Request
+		if body == "" {
+			r = httptest.NewRequest(method, target, nil)
+		} else {
+			r = httptest.NewRequest(method, target, strings.NewReader(body))
+			r.Header.Set("Content-Type", MIMEJSON)
+		}
+		r.Header.Set("X-Request-Id", "req-1")
+		r.Header.Add("X-Tag", "a")
+		r.Header.Add("X-Tag", "b")
+		r.AddCookie(&http.Cookie{Name: "session", Value: "s-1"})
+		return r
+	}
+
+	t.Run("all sources", func(t *testing.T) {
+		r := newRequest(http.MethodPost, "http://example.com/users/1?p=2", `{"id":"body","name":"bar","page":1}`)
+		got := BindAllMode{}
+		err := registry.BindAll(r, url.Values{"id": []string{"path"}}, &got)
+		require.NoError(t, err)
+		require.Equal(t, BindAllMode{
+			BindAllMeta: BindAllMeta{RequestId: "req-1"},
+			Id:          "path",
+			Name:        "bar",
+			Page:        2,
+			Session:     "s-1",
+			Tags:        []string{"a", "b"},
+		}, got)
+	})
+	t.Run("precedence", func(t *testing.T) {
+		r := newRequest(http.MethodPost, "http://example.com/users/1?name=query&id=query&session=query&page=0", `{"name":"body","page":1}`)
+		got := BindAllMode{}
+		err := registry.BindAll(r, nil, &got)
+		require.NoError(t, err)
+		require.Equal(t, "body", got.Name, "the body wins over the query")
+		require.Equal(t, 1, got.Page, "the body wins over the query")
+		require.Equal(t, "query", got.Id, "the query fills the fields missing in the body")
+		require.Equal(t, "s-1", got.Session)
+
+		r = newRequest(http.MethodPost, "http://example.com/users/1?p=3", `{"page":1}`)
+		got = BindAllMode{}
+		require.NoError(t, registry.BindAll(r, nil, &got))
+		require.Equal(t, 3, got.Page, "the tagged query wins over the body")
+	})
+	t.Run("self-referential embedding", func(t *testing.T) {
+		type node struct {
+			*node
+			Name string `json:"name" header:"X-Request-Id"`
+		}
+		fields := sourceFields(reflect.TypeOf(node{}))
+		require.Equal(t, []sourceField{{source: TagHeader, name: "X-Request-Id"}}, fields)
+	})
+	t.Run("no body", func(t *testing.T) {
+		r := newRequest(http.MethodGet, "http://example.com/users/1?name=query", "")
+		got := BindAllMode{}
+		err := registry.BindAll(r, url.Values{"id": []string{"1"}}, &got)
+		require.NoError(t, err)
+		require.Equal(t, "query", got.Name)
+		require.Equal(t, "1", got.Id)
+		require.Equal(t, "req-1", got.RequestId)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		r := newRequest(http.MethodGet, "http://example.com", "")
+		require.Error(t, registry.BindAll(r, nil, BindAllMode{}))
+	})
+}
+
+func Test_Encoding_BindProto(t *testing.T) {
+	registry := New()
+	require.NoError(t, registry.Register(MIMEJSON, &jsonpb.Codec{}))
+	newRequest := func(method, target, body string) *http.Request {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("Content-Type", MIMEJSON)
+		return r
+	}
+	pathVars := url.Values{"name": []string{"path"}}
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		sel     string
+		want    *examplepb.HelloRequest
+		wantErr bool
+	}{
+		{
+			"body *",
+			http.MethodPost,
+			"http://example.com?sub.naming=query",
+			`{"name":"body","sub":{"naming":"body"}}`,
+			"*",
+			&examplepb.HelloRequest{Name: "path", Sub: &examplepb.Sub{Name: "body"}},
+			false,
+		},
+		{
+			"no body",
+			http.MethodGet,
+			"http://example.com?sub.naming=query",
+			"",
+			"",
+			&examplepb.HelloRequest{Name: "path", Sub: &examplepb.Sub{Name: "query"}},
+			false,
+		},
+		{
+			"body field",
+			http.MethodPost,
+			"http://example.com?sub.naming=query&name=query",
+			`{"naming":"body"}`,
+			"sub",
+			&examplepb.HelloRequest{Name: "path", Sub: &examplepb.Sub{Name: "body"}},
+			false,
+		},
+		{
+			"body field not found",
+			http.MethodPost,
+			"http://example.com",
+			`{}`,
+			"unknown",
+			nil,
+			true,
+		},
+		{
+			"body field not message",
+			http.MethodPost,
+			"http://example.com",
+			`{}`,
+			"name",
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &examplepb.HelloRequest{}
+			err := registry.BindProto(newRequest(tt.method, tt.target, tt.body), pathVars, got, tt.sel)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want.GetName(), got.GetName())
+			require.Equal(t, tt.want.GetSub().GetName(), got.GetSub().GetName())
+		})
+	}
+
+	t.Run("BindAll", func(t *testing.T) {
+		got := &examplepb.HelloRequest{}
+		err := registry.BindAll(newRequest(http.MethodPost, "http://example.com?sub.naming=query", `{"sub":{"naming":"body"}}`), pathVars, got)
+		require.NoError(t, err)
+		require.Equal(t, "path", got.GetName())
+		require.Equal(t, "body", got.GetSub().GetName())
+
+		got = &examplepb.HelloRequest{}
+		err = registry.BindAll(httptest.NewRequest(http.MethodGet, "http://example.com?sub.naming=query", nil), pathVars, got)
+		require.NoError(t, err)
+		require.Equal(t, "query", got.GetSub().GetName())
+	})
+}
+
+func Test_Encoding_Bind_Method(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		body          io.Reader
+		contentLength int64
+		want          TestMode
+	}{
+		{"GET", http.MethodGet, nil, 0, TestMode{Id: "query"}},
+		{"HEAD", http.MethodHead, nil, 0, TestMode{Id: "query"}},
+		{"OPTIONS", http.MethodOptions, nil, 0, TestMode{Id: "query"}},
+		{"DELETE without body", http.MethodDelete, nil, 0, TestMode{Id: "query"}},
+		{"POST with Content-Length: 0", http.MethodPost, strings.NewReader(""), 0, TestMode{Id: "query"}},
+		{"POST with unknown length empty body", http.MethodPost, strings.NewReader(""), -1, TestMode{Id: "query"}},
+		{"POST with unknown length body", http.MethodPost, strings.NewReader(`{"name":"body"}`), -1, TestMode{Name: "body"}},
+		{"DELETE with body", http.MethodDelete, strings.NewReader(`{"name":"body"}`), 15, TestMode{Name: "body"}},
+	}
+	registry := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "http://example.com?id=query", tt.body)
+			r.Header.Set("Content-Type", MIMEJSON)
+			r.ContentLength = tt.contentLength
+			got := TestMode{}
+			require.NoError(t, registry.Bind(r, &got))
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_Encoding_Bind_MergeQuery(t *testing.T) {
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "http://example.com?id=query&name=query", strings.NewReader(`{"name":"body"}`))
+		r.Header.Set("Content-Type", MIMEJSON)
+		return r
+	}
+	got := TestMode{}
+	require.NoError(t, New().Bind(newRequest(), &got))
+	require.Equal(t, TestMode{Name: "body"}, got)
+
+	got = TestMode{}
+	require.NoError(t, New().SetMergeQuery(true).Bind(newRequest(), &got))
+	require.Equal(t, TestMode{Id: "query", Name: "body"}, got)
+
+	t.Run("proto", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "http://example.com?name=query", strings.NewReader(`{"sub":{"naming":"body"}}`))
+		r.Header.Set("Content-Type", MIMEJSON)
+		registry := New().SetMergeQuery(true)
+		require.NoError(t, registry.Register(MIMEJSON, &jsonpb.Codec{}))
+		got := &examplepb.HelloRequest{}
+		require.NoError(t, registry.Bind(r, got))
+		require.Equal(t, "query", got.GetName())
+		require.Equal(t, "body", got.GetSub().GetName())
+	})
+}
+
+func Test_Encoding_Bind_FieldErrorSource(t *testing.T) {
+	type value struct {
+		Page  int `json:"page"`
+		Limit int `json:"limit" header:"X-Limit"`
+	}
+	registry := New()
+
+	r := httptest.NewRequest(http.MethodPost, "http://example.com", strings.NewReader(`{"page":"abc"}`))
+	r.Header.Set("Content-Type", MIMEJSON)
+	var fe *codec.FieldError
+	require.ErrorAs(t, registry.Bind(r, &value{}), &fe)
+	require.Equal(t, codec.SourceBody, fe.Source)
+	require.Equal(t, "page", fe.Field)
+
+	r = httptest.NewRequest(http.MethodGet, "http://example.com?page=abc", nil)
+	require.ErrorAs(t, registry.Bind(r, &value{}), &fe)
+	require.Equal(t, codec.SourceQuery, fe.Source)
+
+	require.ErrorAs(t, registry.BindUri(url.Values{"page": {"abc"}}, &value{}), &fe)
+	require.Equal(t, codec.SourcePath, fe.Source)
+
+	r = httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	r.Header.Set("X-Limit", "abc")
+	require.ErrorAs(t, registry.BindAll(r, nil, &value{}), &fe)
+	require.Equal(t, codec.SourceHeader, fe.Source)
+	require.Equal(t, "X-Limit", fe.Field)
+}
@@ -0,0 +1,137 @@
+package encoding
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// renderBufferSize is the size of the body buffered before the response is committed.
+const renderBufferSize = 32 << 10
+
+// maxRenderBufferSize is the maximum capacity of the buffer put back to the pool,
+// the larger buffers which hold a whole large body are dropped.
+const maxRenderBufferSize = 64 << 10
+
+var renderBufferPool = sync.Pool{
+	New: func() any {
+		return bytes.NewBuffer(make([]byte, 0, renderBufferSize))
+	},
+}
+
+// putRenderBuffer resets the buffer and puts it back to the pool unless it is too large.
+func putRenderBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxRenderBufferSize {
+		return
+	}
+	buf.Reset()
+	renderBufferPool.Put(buf)
+}
+
+// responseBuffer is an io.Writer which buffers the body in a pooled buffer until
+// it exceeds renderBufferSize, then it commits the response and writes through
+// to the http.ResponseWriter.
+// So an error occurs before committed can still be turned into an error response.
+// If a compressor is set, the body is compressed when committed if it is at least minSize.
+// When committed, the extra header and status are written, and the `Content-Length` is set
+// if the whole body is buffered and not compressed.
+type responseBuffer struct {
+	w         http.ResponseWriter
+	buf       *bytes.Buffer
+	committed bool
+	status    int
+	header    http.Header
+
+	coding     string
+	compressor Compressor
+	minSize    int
+	cw         io.WriteCloser // the compressed writer once committed.
+}
+
+func newResponseBuffer(w http.ResponseWriter) *responseBuffer {
+	return &responseBuffer{
+		w:   w,
+		buf: renderBufferPool.Get().(*bytes.Buffer),
+	}
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	if !b.committed {
+		if b.buf.Len()+len(p) <= renderBufferSize {
+			return b.buf.Write(p)
+		}
+		if err := b.commit(true); err != nil {
+			return 0, err
+		}
+	}
+	if b.cw != nil {
+		return b.cw.Write(p)
+	}
+	return b.w.Write(p)
+}
+
+// commit commits the response and writes the buffered body,
+// overflow reports whether the body exceeds the buffer.
+func (b *responseBuffer) commit(overflow bool) error {
+	b.committed = true
+	if b.compressor != nil && (overflow || b.buf.Len() >= b.minSize) {
+		cw, err := b.compressor.NewWriter(b.w)
+		if err != nil {
+			return err
+		}
+		header := b.w.Header()
+		header.Set(contentEncodingHeader, b.coding)
+		header.Del(contentLengthHeader)
+		b.cw = cw
+	}
+	copyHeader(b.w.Header(), b.header)
+	if !overflow && b.cw == nil {
+		b.w.Header().Set(contentLengthHeader, strconv.Itoa(b.buf.Len()))
+	}
+	if b.status != 0 {
+		b.w.WriteHeader(b.status)
+	}
+	if b.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if b.cw != nil {
+		_, err = b.cw.Write(b.buf.Bytes())
+	} else {
+		_, err = b.w.Write(b.buf.Bytes())
+	}
+	b.buf.Reset()
+	return err
+}
+
+// Close commits the response if not yet, writes the buffered body,
+// and flushes the compressed body.
+func (b *responseBuffer) Close() error {
+	if !b.committed {
+		if err := b.commit(false); err != nil {
+			return err
+		}
+	}
+	if b.cw == nil {
+		return nil
+	}
+	err := b.cw.Close()
+	b.cw = nil
+	return err
+}
+
+// Committed reports whether the response has been committed.
+func (b *responseBuffer) Committed() bool { return b.committed }
+
+// Release discards the buffered body and puts the buffer back to the pool,
+// the responseBuffer should not be used after released.
+func (b *responseBuffer) Release() {
+	if b.cw != nil { // encoding failed after committed.
+		discardWriter(b.cw)
+		b.cw = nil
+	}
+	putRenderBuffer(b.buf)
+	b.buf = nil
+}
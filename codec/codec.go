@@ -48,6 +48,15 @@ type UriMarshaler interface {
 	UriEncoder
 }
 
+// ParamsMarshaler is implemented by the marshalers which are configured by the media type
+// parameters, e.g. "application/x-protobuf; delimited=true".
+type ParamsMarshaler interface {
+	Marshaler
+	// WithParams returns the marshaler configured by the media type parameters,
+	// the unknown parameters are ignored.
+	WithParams(params map[string]string) Marshaler
+}
+
 // Decoder decodes a byte sequence
 type Decoder interface {
 	Decode(v any) error
@@ -0,0 +1,126 @@
+package codec
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Sources of the FieldError.
+const (
+	SourceBody   = "body"
+	SourceQuery  = "query"
+	SourcePath   = "path"
+	SourceHeader = "header"
+	SourceCookie = "cookie"
+)
+
+// ErrTooLarge is wrapped by the errors of the codecs which report a value exceeds
+// a size limit, e.g. proto.ErrMessageTooLarge, see NewTooLargeError.
+var ErrTooLarge = errors.New("codec: too large")
+
+// NewTooLargeError returns an error of the text which wraps ErrTooLarge.
+func NewTooLargeError(text string) error {
+	return &tooLargeError{text: text}
+}
+
+type tooLargeError struct {
+	text string
+}
+
+func (e *tooLargeError) Error() string { return e.text }
+
+func (e *tooLargeError) Unwrap() error { return ErrTooLarge }
+
+// FieldError records a failed decoding of a field.
+// Every codec in the module reports it on decode failures,
+// the fields which the codec can not tell are left empty.
+type FieldError struct {
+	// Source is where the value comes from, e.g. SourceBody, SourceQuery,
+	// it is filled by the caller knows it, see SetSource.
+	Source string
+	// Field is the dotted field path, e.g. "sub.name", empty if the error is not
+	// specific to a field, e.g. a syntax error.
+	Field string
+	// Value is the offending value.
+	Value string
+	// Type is the expected type, e.g. "int64".
+	Type string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	var b strings.Builder
+
+	b.WriteString("decode")
+	if e.Source != "" {
+		b.WriteString(" " + e.Source)
+	}
+	if e.Field != "" {
+		b.WriteString(" field " + strconv.Quote(e.Field))
+	}
+	if e.Value != "" {
+		b.WriteString(" value " + strconv.Quote(e.Value))
+	}
+	if e.Type != "" {
+		b.WriteString(" into " + e.Type)
+	}
+	if e.Err != nil {
+		b.WriteString(": " + e.Err.Error())
+	}
+	return b.String()
+}
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// FieldErrors collects all the FieldError of a decoding, rather than stopping at the first.
+type FieldErrors []*FieldError
+
+func (e FieldErrors) Error() string {
+	s := make([]string, 0, len(e))
+	for _, fe := range e {
+		s = append(s, fe.Error())
+	}
+	return strings.Join(s, "; ")
+}
+
+func (e FieldErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, fe := range e {
+		errs = append(errs, fe)
+	}
+	return errs
+}
+
+// Err returns nil if there is no FieldError, otherwise returns e.
+func (e FieldErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// AsFieldErrors returns all the FieldError of err,
+// err should be a *FieldError or FieldErrors, or wraps one of them.
+func AsFieldErrors(err error) FieldErrors {
+	var fes FieldErrors
+	if errors.As(err, &fes) {
+		return fes
+	}
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		return FieldErrors{fe}
+	}
+	return nil
+}
+
+// SetSource sets the Source of all the FieldError of err if not set, and returns err.
+func SetSource(err error, source string) error {
+	for _, fe := range AsFieldErrors(err) {
+		if fe.Source == "" {
+			fe.Source = source
+		}
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package codec
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldError_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *FieldError
+		want string
+	}{
+		{
+			"full",
+			&FieldError{Source: SourceQuery, Field: "sub.id", Value: "abc", Type: "int64", Err: errors.New("invalid syntax")},
+			`decode query field "sub.id" value "abc" into int64: invalid syntax`,
+		},
+		{
+			"syntax",
+			&FieldError{Err: io.ErrUnexpectedEOF},
+			"decode: unexpected EOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.err.Error())
+		})
+	}
+}
+
+func TestFieldErrors(t *testing.T) {
+	require.NoError(t, FieldErrors(nil).Err())
+
+	errs := FieldErrors{
+		{Field: "a", Err: io.ErrUnexpectedEOF},
+		{Field: "b", Err: io.EOF},
+	}
+	err := errs.Err()
+	require.Equal(t, `decode field "a": unexpected EOF; decode field "b": EOF`, err.Error())
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.ErrorIs(t, err, io.EOF)
+
+	var fe *FieldError
+	require.ErrorAs(t, err, &fe)
+	require.Equal(t, "a", fe.Field)
+}
+
+func TestSetSource(t *testing.T) {
+	require.NoError(t, SetSource(nil, SourceBody))
+
+	plain := errors.New("plain")
+	require.Equal(t, plain, SetSource(plain, SourceBody))
+
+	fe := &FieldError{Field: "a", Err: plain}
+	require.Equal(t, SourceBody, AsFieldErrors(SetSource(fe, SourceBody))[0].Source)
+
+	errs := FieldErrors{{Field: "a"}, {Field: "b", Source: SourceHeader}}
+	require.Len(t, AsFieldErrors(SetSource(errs, SourceQuery)), 2)
+	require.Equal(t, SourceQuery, errs[0].Source)
+	require.Equal(t, SourceHeader, errs[1].Source)
+}
+
+func TestNewTooLargeError(t *testing.T) {
+	err := NewTooLargeError("foo: too large")
+	require.EqualError(t, err, "foo: too large")
+	require.ErrorIs(t, err, ErrTooLarge)
+}
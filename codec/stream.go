@@ -0,0 +1,82 @@
+package codec
+
+import (
+	"errors"
+	"io"
+	"reflect"
+)
+
+// StreamMarshaler is implemented by the marshalers which support a stream of records,
+// e.g. a JSON array, newline-delimited JSON or length-delimited protobuf messages.
+type StreamMarshaler interface {
+	Marshaler
+	// NewStreamEncoder returns a StreamEncoder which writes a stream of records into "w".
+	NewStreamEncoder(w io.Writer) StreamEncoder
+	// NewStreamDecoder returns a Decoder which reads a stream of records from "r",
+	// a record per Decode, it returns io.EOF at the end of the stream.
+	NewStreamDecoder(r io.Reader) Decoder
+}
+
+// StreamEncoder encodes a stream of records.
+type StreamEncoder interface {
+	// Encode writes a record.
+	Encode(v any) error
+	// Close finishes the stream, e.g. writes the end of a JSON array,
+	// but does not close the underlying writer.
+	Close() error
+}
+
+// Seq is an iterator over sequences of individual values, it is the same as
+// iter.Seq since Go 1.23, so it can be ranged over with Go 1.23 or later.
+type Seq[V any] func(yield func(V) bool)
+
+// Seq2 is an iterator over sequences of pairs of values, it is the same as
+// iter.Seq2 since Go 1.23, so it can be ranged over with Go 1.23 or later.
+type Seq2[K, V any] func(yield func(K, V) bool)
+
+// Iter returns an iterator over the records decoded by dec one at a time until io.EOF,
+// a decode failure is yielded with the zero record and stops the iteration.
+// If T is a pointer, a new value is allocated for each record.
+//
+//	for record, err := range codec.Iter[*pb.Record](dec) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func Iter[T any](dec Decoder) Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			var v T
+			var err error
+			if t := reflect.TypeOf(v); t != nil && t.Kind() == reflect.Pointer {
+				v = reflect.New(t.Elem()).Interface().(T)
+				err = dec.Decode(v)
+			} else {
+				err = dec.Decode(&v)
+			}
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+// SliceSeq returns an iterator over the elements of s without errors.
+func SliceSeq[T any](s []T) Seq2[any, error] {
+	return func(yield func(any, error) bool) {
+		for _, v := range s {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package codec
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// sliceDecoder decodes the ints one at a time.
+type sliceDecoder struct {
+	values []int
+	err    error
+}
+
+func (d *sliceDecoder) Decode(v any) error {
+	if len(d.values) == 0 {
+		if d.err != nil {
+			return d.err
+		}
+		return io.EOF
+	}
+	switch v := v.(type) {
+	case *int:
+		*v = d.values[0]
+	case **int:
+		**v = d.values[0]
+	}
+	d.values = d.values[1:]
+	return nil
+}
+
+func TestIter(t *testing.T) {
+	var got []int
+	Iter[int](&sliceDecoder{values: []int{1, 2, 3}})(func(v int, err error) bool {
+		require.NoError(t, err)
+		got = append(got, v)
+		return v < 2
+	})
+	require.Equal(t, []int{1, 2}, got)
+
+	got = nil
+	var gotErr error
+	Iter[*int](&sliceDecoder{values: []int{1}, err: io.ErrUnexpectedEOF})(func(v *int, err error) bool {
+		if err != nil {
+			require.Nil(t, v)
+			gotErr = err
+			return true
+		}
+		got = append(got, *v)
+		return true
+	})
+	require.Equal(t, []int{1}, got)
+	require.True(t, errors.Is(gotErr, io.ErrUnexpectedEOF))
+}
+
+func TestSliceSeq(t *testing.T) {
+	var got []any
+	SliceSeq([]string{"a", "b", "c"})(func(v any, err error) bool {
+		require.NoError(t, err)
+		got = append(got, v)
+		return len(got) < 2
+	})
+	require.Equal(t, []any{"a", "b"}, got)
+}
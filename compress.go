@@ -0,0 +1,288 @@
+package encoding
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Content codings, see RFC 9110 section 8.4.1.
+const (
+	EncodingGzip     = "gzip"
+	EncodingDeflate  = "deflate"
+	EncodingIdentity = "identity"
+)
+
+// defaultCompressMinSize is the default minimum body size to compress.
+const defaultCompressMinSize = 1024
+
+var (
+	contentEncodingHeader = http.CanonicalHeaderKey("Content-Encoding")
+	acceptEncodingHeader  = http.CanonicalHeaderKey("Accept-Encoding")
+	varyHeader            = http.CanonicalHeaderKey("Vary")
+	contentLengthHeader   = http.CanonicalHeaderKey("Content-Length")
+)
+
+// Compressor compresses and decompresses a content coding, e.g. gzip.
+type Compressor interface {
+	// NewReader returns a reader which decompresses the r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+	// NewWriter returns a writer which compresses into the w,
+	// it should be closed to flush the compressed data.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// NewGzipCompressor returns a gzip Compressor with the compression level,
+// see compress/gzip for the valid levels.
+// The writers are pooled, as they are expensive to allocate.
+func NewGzipCompressor(level int) Compressor {
+	return &gzipCompressor{level: level}
+}
+
+type gzipCompressor struct {
+	level int
+	pool  sync.Pool
+}
+
+func (c *gzipCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func (c *gzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if zw, ok := c.pool.Get().(*gzip.Writer); ok {
+		zw.Reset(w)
+		return &pooledWriter{WriteCloser: zw, put: func() { c.pool.Put(zw) }}, nil
+	}
+	zw, err := gzip.NewWriterLevel(w, c.level)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledWriter{WriteCloser: zw, put: func() { c.pool.Put(zw) }}, nil
+}
+
+// NewDeflateCompressor returns a deflate Compressor with the compression level,
+// see compress/zlib for the valid levels.
+// NOTE: the "deflate" content coding is the zlib format, see RFC 9110 section 8.4.1.2.
+// The writers are pooled, as they are expensive to allocate.
+func NewDeflateCompressor(level int) Compressor {
+	return &deflateCompressor{level: level}
+}
+
+type deflateCompressor struct {
+	level int
+	pool  sync.Pool
+}
+
+func (c *deflateCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReader(r)
+}
+
+func (c *deflateCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if zw, ok := c.pool.Get().(*zlib.Writer); ok {
+		zw.Reset(w)
+		return &pooledWriter{WriteCloser: zw, put: func() { c.pool.Put(zw) }}, nil
+	}
+	zw, err := zlib.NewWriterLevel(w, c.level)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledWriter{WriteCloser: zw, put: func() { c.pool.Put(zw) }}, nil
+}
+
+// pooledWriter puts the writer back to the pool when closed.
+type pooledWriter struct {
+	io.WriteCloser
+	put func()
+}
+
+func (w *pooledWriter) Close() error {
+	err := w.WriteCloser.Close()
+	w.put()
+	return err
+}
+
+// discard resets the writer to io.Discard without flushing it, and puts it back to the pool.
+func (w *pooledWriter) discard() {
+	if zw, ok := w.WriteCloser.(interface{ Reset(io.Writer) }); ok {
+		zw.Reset(io.Discard)
+		w.put()
+	}
+}
+
+// discardWriter abandons the compressed writer of a failed body, it is not closed,
+// so no trailer is written after the truncated body.
+func discardWriter(cw io.WriteCloser) {
+	if pw, ok := cw.(*pooledWriter); ok {
+		pw.discard()
+	}
+}
+
+// decompressedBody closes both the decompressor and the original body.
+type decompressedBody struct {
+	io.ReadCloser
+	body io.ReadCloser
+}
+
+func (b *decompressedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if e := b.body.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// contentCoding is a parsed element of an `Accept-Encoding` header, see RFC 9110 section 12.5.3.
+type contentCoding struct {
+	// name is the lower-cased content coding, or "*".
+	name string
+	// q is the quality value in range [0, 1].
+	q float64
+	// index is the position of the content coding in the header.
+	index int
+}
+
+// parseAcceptEncoding parses the `Accept-Encoding` header values into content codings.
+// Invalid content codings are ignored.
+func parseAcceptEncoding(values []string) []*contentCoding {
+	var codings []*contentCoding
+
+	for _, value := range values {
+		for _, s := range splitHeader(value) {
+			name, params, _ := strings.Cut(s, ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			q := 1.0
+			valid := true
+			for _, param := range strings.Split(params, ";") {
+				k, v, _ := strings.Cut(param, "=")
+				if strings.EqualFold(strings.TrimSpace(k), "q") {
+					var err error
+					q, err = strconv.ParseFloat(strings.TrimSpace(v), 64)
+					valid = err == nil && q >= 0 && q <= 1
+				}
+			}
+			if !valid {
+				continue
+			}
+			codings = append(codings, &contentCoding{name: name, q: q, index: len(codings)})
+		}
+	}
+	return codings
+}
+
+// codingQuality returns the quality of the content coding and the content coding matched it,
+// an explicit content coding takes precedence over "*".
+// It returns nil content coding if none matched.
+func codingQuality(codings []*contentCoding, name string) (float64, *contentCoding) {
+	var wildcard *contentCoding
+	for _, c := range codings {
+		if c.name == name {
+			return c.q, c
+		}
+		if c.name == "*" && wildcard == nil {
+			wildcard = c
+		}
+	}
+	if wildcard == nil {
+		return 0, nil
+	}
+	return wildcard.q, wildcard
+}
+
+// negotiateEncoding returns the negotiated content coding and compressor for this request.
+// It returns empty and nil compressor if the response should not be compressed.
+func (r *registry) negotiateEncoding(req *http.Request) (string, Compressor) {
+	if r.compressMinSize < 0 || len(r.compressors) == 0 {
+		return "", nil
+	}
+	codings := parseAcceptEncoding(req.Header[acceptEncodingHeader])
+	if len(codings) == 0 {
+		return "", nil
+	}
+	var best string
+	var bestQ float64
+	var bestIndex int
+	for _, name := range r.compressorOrder {
+		q, matched := codingQuality(codings, name)
+		if matched == nil || q <= 0 {
+			continue
+		}
+		if best == "" || q > bestQ || (q == bestQ && matched.index < bestIndex) {
+			best, bestQ, bestIndex = name, q, matched.index
+		}
+	}
+	if best == "" {
+		return "", nil
+	}
+	return best, r.compressors[best]
+}
+
+// responseCompressor returns the negotiated content coding and Compressor of the response,
+// and adds the `Vary: Accept-Encoding`, if the compression is enabled and neither header
+// nor extra has `Content-Encoding`, otherwise it returns nil Compressor.
+func (r *registry) responseCompressor(req *http.Request, header, extra http.Header) (string, Compressor) {
+	if r.compressMinSize < 0 || len(r.compressors) == 0 ||
+		header.Get(contentEncodingHeader) != "" || extra.Get(contentEncodingHeader) != "" {
+		return "", nil
+	}
+	addVary(header, acceptEncodingHeader)
+	return r.negotiateEncoding(req)
+}
+
+// decompressBody replaces the request body with the decompressed one
+// according to the `Content-Encoding` header.
+// Multiple content codings are decoded in the reverse order they were applied.
+// An unregistered content coding reports ErrUnsupportedContentEncoding.
+func (r *registry) decompressBody(req *http.Request) error {
+	var codings []string
+	for _, value := range req.Header[contentEncodingHeader] {
+		for _, s := range splitHeader(value) {
+			name := strings.ToLower(s)
+			switch name {
+			case EncodingIdentity:
+			case "x-gzip": // see RFC 9110 section 8.4.1.3.
+				codings = append(codings, EncodingGzip)
+			default:
+				codings = append(codings, name)
+			}
+		}
+	}
+	if len(codings) == 0 || req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	for i := len(codings) - 1; i >= 0; i-- {
+		c, ok := r.compressors[codings[i]]
+		if !ok {
+			return &ContentEncodingError{ContentEncoding: codings[i], Supported: slices.Clone(r.compressorOrder)}
+		}
+		rc, err := c.NewReader(req.Body)
+		if err != nil {
+			return err
+		}
+		req.Body = &decompressedBody{ReadCloser: rc, body: req.Body}
+	}
+	// like the http.Transport does, the body is no longer encoded.
+	req.Header.Del(contentEncodingHeader)
+	req.Header.Del(contentLengthHeader)
+	req.ContentLength = -1
+	return nil
+}
+
+// addVary adds the header name to the `Vary` header if not present.
+func addVary(header http.Header, name string) {
+	for _, value := range header[varyHeader] {
+		for _, s := range splitHeader(value) {
+			if s == "*" || strings.EqualFold(s, name) {
+				return
+			}
+		}
+	}
+	header.Add(varyHeader, name)
+}
@@ -0,0 +1,234 @@
+package encoding
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseAcceptEncoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []contentCoding
+	}{
+		{
+			"",
+			[]string{"gzip, deflate;q=0.5, br;q=0"},
+			[]contentCoding{{"gzip", 1, 0}, {"deflate", 0.5, 1}, {"br", 0, 2}},
+		},
+		{
+			"case insensitive",
+			[]string{"GZIP;Q=0.8", "*"},
+			[]contentCoding{{"gzip", 0.8, 0}, {"*", 1, 1}},
+		},
+		{
+			"invalid ignored",
+			[]string{"gzip;q=2, deflate;q=abc, ;q=1, identity"},
+			[]contentCoding{{"identity", 1, 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []contentCoding
+			for _, c := range parseAcceptEncoding(tt.values) {
+				got = append(got, *c)
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_Encoding_NegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		want           string
+	}{
+		{"empty", "", ""},
+		{"gzip", "gzip", EncodingGzip},
+		{"quality", "gzip;q=0.5, deflate", EncodingDeflate},
+		{"header order", "deflate, gzip", EncodingDeflate},
+		{"wildcard", "*", EncodingGzip},
+		{"wildcard exclusion", "*, gzip;q=0", EncodingDeflate},
+		{"explicit preferred", "*;q=0.5, deflate", EncodingDeflate},
+		{"identity only", "identity", ""},
+		{"unknown", "br", ""},
+	}
+	reg := New().load()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			got, c := reg.negotiateEncoding(r)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.want != "", c != nil)
+		})
+	}
+}
+
+func Test_Encoding_Bind_ContentEncoding(t *testing.T) {
+	payload := `{"id":"foo","name":"bar"}`
+	gzipped := func(b []byte) []byte {
+		buf := &bytes.Buffer{}
+		w := gzip.NewWriter(buf)
+		_, _ = w.Write(b)
+		_ = w.Close()
+		return buf.Bytes()
+	}
+	deflated := func(b []byte) []byte {
+		buf := &bytes.Buffer{}
+		w := zlib.NewWriter(buf)
+		_, _ = w.Write(b)
+		_ = w.Close()
+		return buf.Bytes()
+	}
+	newRequest := func(contentEncoding string, body []byte) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "http://example.com", bytes.NewReader(body))
+		r.Header.Set("Content-Type", MIMEJSON)
+		r.Header.Set("Content-Encoding", contentEncoding)
+		return r
+	}
+
+	tests := []struct {
+		name            string
+		contentEncoding string
+		body            []byte
+	}{
+		{"identity", "identity", []byte(payload)},
+		{"gzip", "gzip", gzipped([]byte(payload))},
+		{"x-gzip", "x-gzip", gzipped([]byte(payload))},
+		{"deflate", "deflate", deflated([]byte(payload))},
+		{"multiple", "deflate, gzip", gzipped(deflated([]byte(payload)))},
+	}
+	registry := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRequest(tt.contentEncoding, tt.body)
+			got := TestMode{}
+			require.NoError(t, registry.Bind(r, &got))
+			require.Equal(t, TestMode{Id: "foo", Name: "bar"}, got)
+		})
+	}
+	t.Run("unsupported", func(t *testing.T) {
+		got := TestMode{}
+		err := registry.Bind(newRequest("br", []byte(payload)), &got)
+		require.ErrorIs(t, err, ErrUnsupportedContentEncoding)
+		var ceErr *ContentEncodingError
+		require.ErrorAs(t, err, &ceErr)
+		require.Equal(t, "br", ceErr.ContentEncoding)
+		require.Equal(t, []string{EncodingGzip, EncodingDeflate}, ceErr.Supported)
+
+		ceErr.Supported[0] = "br"
+		err = registry.Bind(newRequest("br", []byte(payload)), &got)
+		require.ErrorAs(t, err, &ceErr)
+		require.Equal(t, []string{EncodingGzip, EncodingDeflate}, ceErr.Supported, "the registry is not modified")
+	})
+	t.Run("limit decompressed size", func(t *testing.T) {
+		registry := New().SetLimits(Limits{MaxBodySize: 1024})
+		bomb := gzipped(bytes.Repeat([]byte(" "), 1<<20))
+		require.Less(t, len(bomb), 1024*4)
+		got := TestMode{}
+		err := registry.Bind(newRequest("gzip", bomb), &got)
+		require.ErrorIs(t, err, ErrBodyTooLarge)
+	})
+}
+
+func Test_Encoding_Render_Compress(t *testing.T) {
+	small := &TestMode{Id: "foo", Name: "bar"}
+	large := &TestMode{Id: "foo", Name: strings.Repeat("bar", 1024)}
+	decode := func(t *testing.T, w *httptest.ResponseRecorder) string {
+		switch w.Header().Get("Content-Encoding") {
+		case EncodingGzip:
+			r, err := gzip.NewReader(w.Body)
+			require.NoError(t, err)
+			b, err := io.ReadAll(r)
+			require.NoError(t, err)
+			return string(b)
+		case EncodingDeflate:
+			r, err := zlib.NewReader(w.Body)
+			require.NoError(t, err)
+			b, err := io.ReadAll(r)
+			require.NoError(t, err)
+			return string(b)
+		default:
+			return w.Body.String()
+		}
+	}
+
+	tests := []struct {
+		name           string
+		registry       *Encoding
+		acceptEncoding string
+		v              any
+		want           string
+	}{
+		{"no accept encoding", New(), "", large, ""},
+		{"below min size", New(), "gzip", small, ""},
+		{"gzip", New(), "gzip", large, EncodingGzip},
+		{"deflate", New(), "gzip;q=0.5, deflate", large, EncodingDeflate},
+		{"min size", New().SetCompressMinSize(0), "gzip", small, EncodingGzip},
+		{"disabled", New().SetCompressMinSize(-1), "gzip", large, ""},
+		{"exceeds buffer", New().SetCompressMinSize(1 << 20), "gzip", &TestMode{Name: strings.Repeat("a", renderBufferSize)}, EncodingGzip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			w := httptest.NewRecorder()
+			require.NoError(t, tt.registry.Render(w, r, tt.v))
+			require.Equal(t, tt.want, w.Header().Get("Content-Encoding"))
+
+			want := &bytes.Buffer{}
+			require.NoError(t, tt.registry.Get(MIMEWildcard).NewEncoder(want).Encode(tt.v))
+			require.Equal(t, want.String(), decode(t, w))
+		})
+	}
+	t.Run("vary", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		w := httptest.NewRecorder()
+		w.Header().Set("Vary", "Origin, accept-encoding")
+		require.NoError(t, New().Render(w, r, small))
+		require.Equal(t, []string{"Origin, accept-encoding", "Accept"}, w.Header().Values("Vary"))
+
+		w = httptest.NewRecorder()
+		require.NoError(t, New().Render(w, r, small))
+		require.Equal(t, []string{"Accept", "Accept-Encoding"}, w.Header().Values("Vary"))
+
+		w = httptest.NewRecorder()
+		require.NoError(t, New().SetCompressMinSize(-1).Render(w, r, small))
+		require.Equal(t, []string{"Accept"}, w.Header().Values("Vary"))
+	})
+	t.Run("already encoded", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		w.Header().Set("Content-Encoding", "br")
+		require.NoError(t, New().Render(w, r, large))
+		require.Equal(t, "br", w.Header().Get("Content-Encoding"))
+	})
+}
+
+func Test_Encoding_RegisterCompressor(t *testing.T) {
+	registry := New()
+	require.Error(t, registry.RegisterCompressor("", NewGzipCompressor(gzip.BestSpeed)))
+	require.Error(t, registry.RegisterCompressor("identity", NewGzipCompressor(gzip.BestSpeed)))
+	require.Error(t, registry.RegisterCompressor("x", nil))
+
+	require.NoError(t, registry.RegisterCompressor("X-Custom", NewGzipCompressor(gzip.BestSpeed)))
+	require.Equal(t, []string{EncodingGzip, EncodingDeflate, "x-custom"}, registry.load().compressorOrder)
+	registry.DeleteCompressor(EncodingGzip)
+	require.Equal(t, []string{EncodingDeflate, "x-custom"}, registry.load().compressorOrder)
+
+	r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	r.Header.Set("Accept-Encoding", "gzip, x-custom")
+	got, _ := registry.load().negotiateEncoding(r)
+	require.Equal(t, "x-custom", got)
+}
@@ -1,12 +1,18 @@
 package encoding
 
 import (
+	"compress/gzip"
+	"compress/zlib"
 	"errors"
 	"fmt"
 	"mime"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/protobuf/proto"
 
 	"github.com/things-go/encoding/codec"
 	"github.com/things-go/encoding/form"
@@ -33,10 +39,29 @@ const (
 	MIMEPOSTForm          = "application/x-www-form-urlencoded"
 	MIMEMultipartPOSTForm = "multipart/form-data"
 	MIMEPROTOBUF          = "application/x-protobuf"
+	MIMEPROTOTEXT         = "text/x-protobuf"
+	MIMEPROTOTEXT2        = "application/x-protobuf-text"
 	MIMEMSGPACK           = "application/x-msgpack"
 	MIMEMSGPACK2          = "application/msgpack"
 	MIMEYAML              = "application/x-yaml"
+	MIMEYAML2             = "application/yaml"
 	MIMETOML              = "application/toml"
+	MIMECBOR              = "application/cbor"
+	MIMENDJSON            = "application/x-ndjson"
+	MIMEJSONL             = "application/jsonl"
+)
+
+// Structured syntax suffixes of media types, see RFC 6839.
+// for example: "application/problem+json" has suffix "+json".
+// Register a marshaler with the suffix (e.g. "+json") to handle all the
+// media types with the suffix explicitly.
+const (
+	SuffixJSON     = "+json"
+	SuffixXML      = "+xml"
+	SuffixYAML     = "+yaml"
+	SuffixCBOR     = "+cbor"
+	SuffixPROTO    = "+proto"
+	SuffixPROTOBUF = "+protobuf"
 )
 
 var (
@@ -45,11 +70,11 @@ var (
 )
 
 // Encoding is a mapping from MIME types to Marshalers.
+// It is safe for concurrent use, the readers load an immutable snapshot of the
+// registry without locking, the writers (Register, Delete, ...) publish an updated copy.
 type Encoding struct {
-	mimeMap      map[string]codec.Marshaler
-	mimeQuery    codec.FormMarshaler
-	mimeUri      codec.UriMarshaler
-	mimeWildcard codec.Marshaler
+	mu       sync.Mutex // serializes the writers.
+	registry atomic.Pointer[registry]
 }
 
 // New encoding with default Marshalers
@@ -62,17 +87,28 @@ type Encoding struct {
 //	mimeUri:   form.UriCodec
 //	mimeWildcard: json.Codec
 //
+// and the default Compressors, compress the response body at least 1024 bytes:
+//
+//	EncodingGzip: gzip Compressor
+//	EncodingDeflate: deflate Compressor
+//
 // you can manually register your custom Marshaler.
 //
 //	MIMEPROTOBUF: proto.Codec
+//	MIMEPROTOTEXT:  prototext.Codec
+//	MIMEPROTOTEXT2: prototext.Codec
 //	MIMEXML:      xml.Codec
 //	MIMEXML2:     xml.Codec
 //	MIMEMSGPACK:  msgpack.Codec
 //	MIMEMSGPACK2: msgpack.Codec
 //	MIMEYAML:     yaml.Codec
+//	MIMEYAML2:    yaml.Codec
 //	MIMETOML:    toml.Codec
+//	MIMENDJSON:   ndjson.Codec
+//	MIMEJSONL:    ndjson.Codec
 func New() *Encoding {
-	return &Encoding{
+	r := &Encoding{}
+	r.registry.Store(&registry{
 		mimeMap: map[string]codec.Marshaler{
 			MIMEPOSTForm:          form.New("json"),
 			MIMEMultipartPOSTForm: &form.MultipartCodec{Codec: form.New("json")},
@@ -81,16 +117,51 @@ func New() *Encoding {
 		mimeQuery:    &form.QueryCodec{Codec: form.New("json")},
 		mimeUri:      &form.UriCodec{Codec: form.New("json")},
 		mimeWildcard: &json.Codec{UseNumber: true, DisallowUnknownFields: true},
-	}
+		compressors: map[string]Compressor{
+			EncodingGzip:    NewGzipCompressor(gzip.DefaultCompression),
+			EncodingDeflate: NewDeflateCompressor(zlib.DefaultCompression),
+		},
+		compressorOrder: []string{EncodingGzip, EncodingDeflate},
+		compressMinSize: defaultCompressMinSize,
+	})
+	return r
+}
+
+// Clone returns a copy of the Encoding, which shares the current registered marshalers
+// and settings, but later modifications on either one do not affect the other.
+// It is useful to derive a per-route Encoding from a shared base.
+func (r *Encoding) Clone() *Encoding {
+	c := &Encoding{}
+	c.registry.Store(r.load())
+	return c
+}
+
+// load returns the current snapshot of the registry.
+func (r *Encoding) load() *registry {
+	return r.registry.Load()
+}
+
+// update applies fn to a copy of the current registry and publishes it.
+func (r *Encoding) update(fn func(reg *registry)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reg := r.load().clone()
+	fn(reg)
+	r.registry.Store(reg)
 }
 
 // Register a marshaler for a case-sensitive MIME type string
-// ("*" to match any MIME type).
-// you can override default marshaler with same MIME type
+// ("*" to match any MIME type, "+json" to match any MIME type with the structured syntax suffix).
+// you can override default marshaler with same MIME type.
+// A codec.ParamsMarshaler is configured by the parameters of the negotiated media type,
+// e.g. "application/x-protobuf; delimited=true" selects the length-delimited protobuf.
 func (r *Encoding) Register(mime string, marshaler codec.Marshaler) error {
 	if len(mime) == 0 {
 		return errors.New("encoding: empty MIME type")
 	}
+	if mime == "+" {
+		return errors.New("encoding: empty structured syntax suffix")
+	}
 	if marshaler == nil {
 		return errors.New("encoding: marshaller should be not nil")
 	}
@@ -100,39 +171,27 @@ func (r *Encoding) Register(mime string, marshaler codec.Marshaler) error {
 		if !ok {
 			return errors.New("encoding: marshaller should be implement codec.FormMarshaler")
 		}
-		r.mimeQuery = m
+		r.update(func(reg *registry) { reg.mimeQuery = m })
 	case MIMEURI:
 		m, ok := marshaler.(codec.UriMarshaler)
 		if !ok {
 			return errors.New("encoding: marshaller should be implement codec.UriMarshaler")
 		}
-		r.mimeUri = m
+		r.update(func(reg *registry) { reg.mimeUri = m })
 	case MIMEWildcard:
-		r.mimeWildcard = marshaler
+		r.update(func(reg *registry) { reg.mimeWildcard = marshaler })
 	default:
-		r.mimeMap[mime] = marshaler
+		r.update(func(reg *registry) { reg.mimeMap[mime] = marshaler })
 	}
 	return nil
 }
 
 // Get returns the marshalers with a case-sensitive MIME type string
-// It checks the MIME type on the Encoding.
+// It checks the MIME type on the Encoding, the structured syntax suffix is resolved
+// if there is no exact entry, e.g. "application/problem+json" to "+json" and "application/json".
 // Otherwise, it follows the above logic for "*" Marshaler.
 func (r *Encoding) Get(mime string) codec.Marshaler {
-	switch mime {
-	case MIMEQuery:
-		return r.mimeQuery
-	case MIMEURI:
-		return r.mimeUri
-	case MIMEWildcard:
-		return r.mimeWildcard
-	default:
-		m := r.mimeMap[mime]
-		if m == nil {
-			m = r.mimeWildcard
-		}
-		return m
-	}
+	return r.load().get(mime)
 }
 
 // Delete remove the MIME type marshaler.
@@ -143,10 +202,128 @@ func (r *Encoding) Delete(mime string) error {
 		mime == MIMEURI {
 		return fmt.Errorf("encoding: MIME(%s) can't delete, but you can override it", mime)
 	}
-	delete(r.mimeMap, mime)
+	r.update(func(reg *registry) { delete(reg.mimeMap, mime) })
 	return nil
 }
 
+// SetStrict set strict mode, default is false.
+// In strict mode, rather than follow the logic for "*" Marshaler,
+// an unregistered `Content-Type` reports ErrUnsupportedMediaType, and
+// an `Accept` which no registered MIME type is acceptable reports ErrNotAcceptable,
+// both are wrapped in *MediaTypeError.
+// A missing `Content-Type` or `Accept` still uses the "*" Marshaler.
+// It takes effect on Lookup, NegotiateInbound, NegotiateOutbound, Encode, Bind and Render.
+func (r *Encoding) SetStrict(strict bool) *Encoding {
+	r.update(func(reg *registry) { reg.strict = strict })
+	return r
+}
+
+// SetLimits set the request body Limits for all MIME types, default is no limit.
+// It takes effect on Bind.
+func (r *Encoding) SetLimits(l Limits) *Encoding {
+	r.update(func(reg *registry) { reg.limits = l })
+	return r
+}
+
+// SetMIMELimits set the request body Limits for the MIME type,
+// it replaces the Limits set by SetLimits entirely for the MIME type.
+// It takes effect on Bind.
+func (r *Encoding) SetMIMELimits(mime string, l Limits) *Encoding {
+	r.update(func(reg *registry) { reg.mimeLimits[mime] = l })
+	return r
+}
+
+// SetMergeQuery set whether Bind binds the query as well as the body, default is false.
+// The query is bound first, then the body overwrites the fields it sets, so the query
+// only fills the fields not in the body, like grpc-gateway does for the unbound fields.
+func (r *Encoding) SetMergeQuery(merge bool) *Encoding {
+	r.update(func(reg *registry) { reg.mergeQuery = merge })
+	return r
+}
+
+// RegisterCompressor register a Compressor for the content coding, e.g. "br".
+// An existing Compressor for the content coding is replaced, but keeps its preference,
+// the Compressors registered earlier are preferred when the `Accept-Encoding` ties.
+// It takes effect on Bind and Render.
+func (r *Encoding) RegisterCompressor(name string, c Compressor) error {
+	name = strings.ToLower(name)
+	if name == "" || name == "*" || name == EncodingIdentity {
+		return fmt.Errorf("encoding: invalid content coding(%q)", name)
+	}
+	if c == nil {
+		return errors.New("encoding: compressor should be not nil")
+	}
+	r.update(func(reg *registry) {
+		if _, ok := reg.compressors[name]; !ok {
+			reg.compressorOrder = append(reg.compressorOrder, name)
+		}
+		reg.compressors[name] = c
+	})
+	return nil
+}
+
+// DeleteCompressor remove the Compressor for the content coding.
+func (r *Encoding) DeleteCompressor(name string) {
+	name = strings.ToLower(name)
+	r.update(func(reg *registry) {
+		if _, ok := reg.compressors[name]; !ok {
+			return
+		}
+		delete(reg.compressors, name)
+		order := reg.compressorOrder[:0]
+		for _, v := range reg.compressorOrder {
+			if v != name {
+				order = append(order, v)
+			}
+		}
+		reg.compressorOrder = order
+	})
+}
+
+// SetCompressMinSize set the minimum response body size to compress, default is 1024 bytes.
+// A negative size disables the response compression, the request decompression is unaffected.
+// It takes effect on Render.
+func (r *Encoding) SetCompressMinSize(size int) *Encoding {
+	r.update(func(reg *registry) { reg.compressMinSize = size })
+	return r
+}
+
+// RegisterProblemMapper register a ProblemMapper to map the domain errors to Problem,
+// the ProblemMappers registered later are tried first.
+// It takes effect on RenderError.
+func (r *Encoding) RegisterProblemMapper(m ProblemMapper) error {
+	if m == nil {
+		return errors.New("encoding: problem mapper should be not nil")
+	}
+	r.update(func(reg *registry) { reg.problemMappers = append(reg.problemMappers, m) })
+	return nil
+}
+
+// Lookup returns the marshalers with a case-sensitive MIME type string.
+// It checks the MIME type on the Encoding like Get.
+// Otherwise, it follows the above logic for "*" Marshaler, or reports
+// ErrUnsupportedMediaType in strict mode.
+func (r *Encoding) Lookup(mime string) (codec.Marshaler, error) {
+	reg := r.load()
+	switch mime {
+	case MIMEQuery, MIMEURI, MIMEWildcard:
+		return reg.get(mime), nil
+	}
+	if m := reg.lookup(mime); m != nil {
+		return m, nil
+	}
+	if reg.strict {
+		return nil, reg.newMediaTypeError(ErrUnsupportedMediaType, mime)
+	}
+	return reg.mimeWildcard, nil
+}
+
+// Registered returns the sorted registered MIME types,
+// the special MIMEWildcard, MIMEQuery and MIMEURI excluded.
+func (r *Encoding) Registered() []string {
+	return r.load().registered()
+}
+
 // InboundForRequest returns the inbound `Content-Type` and marshalers for this request.
 // It checks the registry on the Encoding for the MIME type set by the `Content-Type` header.
 // If it isn't set (or the request `Content-Type` is empty), checks for "*".
@@ -154,17 +331,42 @@ func (r *Encoding) Delete(mime string) error {
 // exactly match in the registry.
 // Otherwise, it follows the above logic for "*" Marshaler.
 func (r *Encoding) InboundForRequest(req *http.Request) (string, codec.Marshaler) {
-	return r.marshalerFromHeaderContentType(req.Header[contentTypeHeader])
+	reg := r.load()
+	contentType, marshaler := reg.marshalerFromHeaderContentType(req.Header[contentTypeHeader])
+	if marshaler == nil {
+		contentType, marshaler = MIMEWildcard, reg.mimeWildcard
+	}
+	return contentType, marshaler
 }
 
 // OutboundForRequest returns the marshalers for this request.
-// It checks the registry on the Encoding for the MIME type set by the `Accept` header.
+// It negotiates with the registry on the Encoding for the media ranges set by the `Accept` header,
+// see RFC 9110 section 12.5.1, the media ranges are ordered by quality and specificity,
+// "type/*" and "*/*" are supported, and a media range with "q=0" excludes the matched MIME type,
+// unless it has parameters, since the registered MIME types have none to compare with.
 // If it isn't set (or the request `Accept` is empty), checks for "*".
-// If there are multiple `Accept` headers set, choose the first one that it can
-// exactly match in the registry.
-// Otherwise, it follows the above logic for "*" Marshaler.
+// If no registered MIME type is acceptable, it follows the above logic for "*" Marshaler.
+// Use NegotiateOutbound for the negotiated media type.
 func (r *Encoding) OutboundForRequest(req *http.Request) codec.Marshaler {
-	return r.marshalerFromHeaderAccept(req.Header[acceptHeader])
+	reg := r.load()
+	_, marshaler := reg.marshalerFromHeaderAccept(req.Header[acceptHeader])
+	if marshaler == nil {
+		marshaler = reg.mimeWildcard
+	}
+	return marshaler
+}
+
+// NegotiateInbound is like InboundForRequest, but in strict mode, it reports
+// ErrUnsupportedMediaType if the request `Content-Type` is set but not registered.
+func (r *Encoding) NegotiateInbound(req *http.Request) (string, codec.Marshaler, error) {
+	return r.load().negotiateInbound(req)
+}
+
+// NegotiateOutbound is like OutboundForRequest, but it returns the negotiated media type too,
+// "*" for the "*" Marshaler, and in strict mode, it reports ErrNotAcceptable if the request
+// `Accept` is set but no registered MIME type is acceptable.
+func (r *Encoding) NegotiateOutbound(req *http.Request) (string, codec.Marshaler, error) {
+	return r.load().negotiateOutbound(req)
 }
 
 // Bind checks the Method and Content-Type to select codec.Marshaler automatically,
@@ -175,33 +377,59 @@ func (r *Encoding) OutboundForRequest(req *http.Request) codec.Marshaler {
 //
 // It parses the request's body as JSON if Content-Type == "application/json" using JSON or XML as a JSON input.
 // It decodes the json payload into the struct specified as a pointer.
+//
+// The request of a safe method (GET, HEAD, OPTIONS and TRACE) or without a body
+// (missing body or `Content-Length: 0`) is bound from the query, see BindQuery,
+// whether SetMergeQuery is set or not, e.g. a POST with an empty body.
+// With SetMergeQuery, the query is also bound before the body, so the body takes precedence,
+// a proto message's body is decoded into a new message and merged over the query bound one,
+// since the proto codecs reset the message.
+//
+// The request body is decompressed according to the `Content-Encoding` header,
+// an unregistered content coding reports *ContentEncodingError.
+// The decompressed request body is limited by the Limits set by SetLimits or SetMIMELimits,
+// exceeding a limit reports *LimitError, a read timeout reports ErrReadTimeout.
+//
+// For "multipart/form-data", the files are bound into the top level struct fields of type
+// *multipart.FileHeader, []*multipart.FileHeader, []byte and io.Reader, named by the `file` tag
+// or the multipart codec's tag, or into the bytes and google.api.HttpBody fields of a proto message.
+// The io.Reader reads the file content in memory, so there is nothing to close.
+// The file limits are checked while each file part is read, see Limits.
+//
+// The decode failures are reported as *codec.FieldError or codec.FieldErrors with the Source filled.
 func (r *Encoding) Bind(req *http.Request, v any) error {
-	if req.Method == http.MethodGet {
-		return r.BindQuery(req, v)
+	reg := r.load()
+	withBody, err := hasBody(req)
+	if err != nil {
+		return err
 	}
-	contentType, marshaller := r.InboundForRequest(req)
-	if contentType == MIMEMultipartPOSTForm {
-		m, ok := marshaller.(codec.FormCodec)
-		if !ok {
-			return fmt.Errorf("encoding: not supported marshaller(%v)", contentType)
-		}
-		if err := req.ParseMultipartForm(defaultMemory); err != nil {
+	if !withBody {
+		return reg.bindQuery(req.URL.Query(), v)
+	}
+	if reg.mergeQuery {
+		if err = reg.bindQuery(req.URL.Query(), v); err != nil {
 			return err
 		}
-		return m.Decode(req.MultipartForm.Value, v)
+		if m, ok := v.(proto.Message); ok {
+			body := m.ProtoReflect().New().Interface()
+			if err = reg.bindBody(req, body); err != nil {
+				return err
+			}
+			proto.Merge(m, body)
+			return nil
+		}
 	}
-	return marshaller.NewDecoder(req.Body).
-		Decode(v)
+	return reg.bindBody(req, v)
 }
 
 // BindQuery binds the passed struct pointer using the query codec.Marshaler.
 func (r *Encoding) BindQuery(req *http.Request, v any) error {
-	return r.mimeQuery.Decode(req.URL.Query(), v)
+	return r.load().bindQuery(req.URL.Query(), v)
 }
 
 // BindUri binds the passed struct pointer using the uri codec.Marshaler.
 func (r *Encoding) BindUri(raws url.Values, v any) error {
-	return r.mimeUri.Decode(raws, v)
+	return r.load().bindPath(raws, v)
 }
 
 // Render writes the response headers and calls the outbound marshalers for this request.
@@ -211,30 +439,44 @@ func (r *Encoding) BindUri(raws url.Values, v any) error {
 //	"application/json" --> JSON codec.Marshaler
 //	"application/xml"  --> XML codec.Marshaler
 //
-// If there are multiple Accept headers set, choose the first one that it can
-// exactly match in the registry.
-// Otherwise, it follows the above logic for "*" Marshaler.
+// The Accept header is negotiated by quality and specificity, see OutboundForRequest,
+// and the Content-Type reflects the negotiated MIME type.
+// Otherwise, it follows the above logic for "*" Marshaler, or reports ErrNotAcceptable in strict mode.
+//
+// The body is streamed into the http.ResponseWriter by the marshaler's Encoder,
+// the first bytes (up to 32KiB) are buffered, so if encoding fails before the
+// response is committed, nothing is written and the `Content-Type` is removed,
+// the caller can still write an error response.
+// NOTE: the body is the Encoder's output, which may differ from the Marshal's, e.g. the
+// JSON body ends with the newline written by json.Encoder, and a delimited proto body
+// is length-prefixed, so the framing negotiated by the media type parameters applies.
+//
+// The body is compressed by the Compressor negotiated by the `Accept-Encoding` header,
+// if it is at least the size set by SetCompressMinSize, a body exceeds the buffer is
+// always compressed, the `Vary: Accept-Encoding` is added whenever compression is enabled.
+// A response with `Content-Encoding` already set is never compressed.
+//
+// The status is 200, the `Vary: Accept` is added, the `Content-Length` is set if the
+// whole body is buffered and not compressed, and the body of a HEAD request is not written.
+// Use RenderWith for the status, extra headers and conditional requests.
 func (r *Encoding) Render(w http.ResponseWriter, req *http.Request, v any) error {
-	if v == nil {
-		return nil
-	}
-	marshaller := r.OutboundForRequest(req)
-	data, err := marshaller.Marshal(v)
-	if err != nil {
-		return err
-	}
-	w.Header().Set("Content-Type", marshaller.ContentType(v))
-	_, err = w.Write(data)
-	return err
+	return r.RenderWith(w, req, v, RenderOptions{})
 }
 
-func parseAcceptHeader(header string) []string {
-	// TODO: cache header maps to avoid parse again?
-	values := strings.Split(header, ",")
-	for i := 0; i < len(values); i++ {
-		values[i] = strings.TrimSpace(values[i])
+// contentTypeFor returns the `Content-Type` of v for the negotiated media type.
+// The marshaler's parameters (e.g. charset) are kept, but the media type is replaced
+// by the negotiated one, unless the marshaler reports a value specific content type,
+// e.g. google.api.HttpBody.
+func contentTypeFor(mediaType string, marshaler codec.Marshaler, v any) string {
+	contentType := marshaler.ContentType(v)
+	if mediaType == MIMEWildcard {
+		return contentType
+	}
+	base, params, err := mime.ParseMediaType(contentType)
+	if err != nil || base == mediaType || base != baseMediaType(marshaler.ContentType(nil)) {
+		return contentType
 	}
-	return values
+	return mime.FormatMediaType(mediaType, params)
 }
 
 // InboundForResponse returns the inbound marshaler for this response.
@@ -244,74 +486,30 @@ func parseAcceptHeader(header string) []string {
 // exactly match in the registry.
 // Otherwise, it follows the above logic for "*" Marshaler.
 func (r *Encoding) InboundForResponse(resp *http.Response) codec.Marshaler {
-	_, marshaler := r.marshalerFromHeaderContentType(resp.Header[contentTypeHeader])
+	reg := r.load()
+	_, marshaler := reg.marshalerFromHeaderContentType(resp.Header[contentTypeHeader])
+	if marshaler == nil {
+		marshaler = reg.mimeWildcard
+	}
 	return marshaler
 }
 
 // Encode encode v use contentType
 func (r *Encoding) Encode(contentType string, v any) ([]byte, error) {
-	return r.Get(contentType).Marshal(v)
+	marshaler, err := r.Lookup(contentType)
+	if err != nil {
+		return nil, err
+	}
+	return marshaler.Marshal(v)
 }
 
 // EncodeQuery encode v to the query url.Values.
 func (r *Encoding) EncodeQuery(v any) (url.Values, error) {
-	return r.mimeQuery.Encode(v)
+	return r.load().mimeQuery.Encode(v)
 }
 
 // EncodeURL encode msg to url path.
 // pathTemplate is a template of url path like http://helloworld.dev/{name}/sub/{sub.name},
 func (r *Encoding) EncodeURL(athTemplate string, msg any, needQuery bool) string {
-	return r.mimeUri.EncodeURL(athTemplate, msg, needQuery)
-}
-
-// marshalerFromHeaderContentType returns the `Content-Type` and marshaler from `Content-Type` header.
-// It checks the registry on the Encoding for the MIME type set by the `Content-Type` header.
-// If it isn't set (or the `Content-Type` is empty), checks for "*".
-// If there are multiple `Content-Type` headers set, choose the first one that it can
-// exactly match in the registry.
-// Otherwise, it follows the above logic for "*" Marshaler.
-func (r *Encoding) marshalerFromHeaderContentType(values []string) (string, codec.Marshaler) {
-	var err error
-	var marshaler codec.Marshaler
-	var contentType string
-
-	for _, contentTypeVal := range values {
-		contentType, _, err = mime.ParseMediaType(contentTypeVal)
-		if err != nil {
-			continue
-		}
-		if m, ok := r.mimeMap[contentType]; ok {
-			marshaler = m
-			break
-		}
-	}
-	if marshaler == nil {
-		contentType = MIMEWildcard
-		marshaler = r.mimeWildcard
-	}
-	return contentType, marshaler
-}
-
-// marshalerFromHeaderAccept returns the marshalers from `Accept` header.
-// It checks the registry on the Encoding for the MIME type set by the `Accept` header.
-// If it isn't set (or the `Accept` is empty), checks for "*".
-// If there are multiple `Accept` headers set, choose the first one that it can
-// exactly match in the registry.
-// Otherwise, it follows the above logic for "*" Marshaler.
-func (r *Encoding) marshalerFromHeaderAccept(values []string) codec.Marshaler {
-	var marshaler codec.Marshaler
-
-	for _, acceptVal := range values {
-		headerValues := parseAcceptHeader(acceptVal)
-		for _, value := range headerValues {
-			if m, ok := r.mimeMap[value]; ok {
-				marshaler = m
-				break
-			}
-		}
-	}
-	if marshaler == nil {
-		marshaler = r.mimeWildcard
-	}
-	return marshaler
+	return r.load().mimeUri.EncodeURL(athTemplate, msg, needQuery)
 }
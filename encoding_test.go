@@ -10,9 +10,11 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protodelim"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
@@ -22,6 +24,7 @@ import (
 	"github.com/things-go/encoding/json"
 	"github.com/things-go/encoding/msgpack"
 	pro "github.com/things-go/encoding/proto"
+	"github.com/things-go/encoding/prototext"
 	"github.com/things-go/encoding/testdata/examplepb"
 	"github.com/things-go/encoding/toml"
 	"github.com/things-go/encoding/xml"
@@ -621,7 +624,8 @@ func Test_Encoding_Render(t *testing.T) {
 					Name: "bar",
 				},
 			},
-			`{"id":"foo","name":"bar"}`,
+			// the body is written by json.Encoder, which appends a newline.
+			`{"id":"foo","name":"bar"}` + "\n",
 			false,
 		},
 	}
@@ -642,32 +646,6 @@ func Test_Encoding_Render(t *testing.T) {
 	}
 }
 
-func Test_ParseAcceptHeader(t *testing.T) {
-	tests := []struct {
-		name   string
-		header string
-		want   []string
-	}{
-		{
-			"",
-			"application/json, text/plain, */*",
-			[]string{"application/json", "text/plain", "*/*"},
-		},
-		{
-			"",
-			"application/json,text/plain,   */*",
-			[]string{"application/json", "text/plain", "*/*"},
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := parseAcceptHeader(tt.header); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseAcceptHeader() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
 func Test_Encoding_InBound_ForResponse_Wildcard(t *testing.T) {
 	var registry = New()
 
@@ -727,3 +705,226 @@ func Test_Encoding_Inbound_ForRequest_NotWildcard(t *testing.T) {
 		})
 	}
 }
+
+func Test_Encoding_Strict(t *testing.T) {
+	registry := New().SetStrict(true)
+
+	t.Run("lookup", func(t *testing.T) {
+		_, err := registry.Lookup("text/csv")
+		require.ErrorIs(t, err, ErrUnsupportedMediaType)
+		m, err := registry.Lookup(MIMEJSON)
+		require.NoError(t, err)
+		require.NotNil(t, m)
+		_, err = registry.Encode("text/csv", &TestMode{})
+		require.ErrorIs(t, err, ErrUnsupportedMediaType)
+	})
+	t.Run("unsupported media type", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "http://example.com", bytes.NewReader([]byte("id,name")))
+		r.Header.Set("Content-Type", "text/csv")
+
+		err := registry.Bind(r, &TestMode{})
+		require.ErrorIs(t, err, ErrUnsupportedMediaType)
+		var e *MediaTypeError
+		require.ErrorAs(t, err, &e)
+		require.Equal(t, "text/csv", e.MediaType)
+		require.Equal(t, registry.Registered(), e.Supported)
+		require.Contains(t, e.Supported, MIMEJSON)
+
+		// not strict falls back to wildcard
+		contentType, m := registry.InboundForRequest(r)
+		require.Equal(t, MIMEWildcard, contentType)
+		require.NotNil(t, m)
+	})
+	t.Run("missing content type", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "http://example.com", bytes.NewReader([]byte(`{"id":"foo"}`)))
+		got := &TestMode{}
+		require.NoError(t, registry.Bind(r, got))
+		require.Equal(t, &TestMode{Id: "foo"}, got)
+	})
+	t.Run("not acceptable", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		r.Header.Set("Accept", "text/csv, application/json;q=0")
+		w := httptest.NewRecorder()
+
+		err := registry.Render(w, r, &TestMode{Id: "foo"})
+		require.ErrorIs(t, err, ErrNotAcceptable)
+		var e *MediaTypeError
+		require.ErrorAs(t, err, &e)
+		require.Equal(t, "text/csv, application/json;q=0", e.MediaType)
+		require.Empty(t, w.Body.String())
+	})
+	t.Run("acceptable", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		w := httptest.NewRecorder()
+
+		err := registry.Render(w, r, &TestMode{Id: "foo"})
+		require.NoError(t, err)
+		// the body is written by json.Encoder, which appends a newline.
+		require.Equal(t, `{"id":"foo","name":""}`+"\n", w.Body.String())
+	})
+}
+
+func Test_Encoding_Clone(t *testing.T) {
+	base := New()
+	require.NoError(t, base.Register(MIMEXML, &xml.Codec{}))
+
+	derived := base.Clone()
+	require.NoError(t, derived.Register(MIMEYAML, &yaml.Codec{}))
+	require.NoError(t, derived.Delete(MIMEXML))
+	derived.SetStrict(true)
+
+	_, ok := base.Get(MIMEXML).(*xml.Codec)
+	require.True(t, ok, "base should keep MIME xml marshaler")
+	_, err := base.Lookup(MIMEYAML)
+	require.NoError(t, err, "base should not be strict")
+	_, ok = base.Get(MIMEYAML).(*json.Codec)
+	require.True(t, ok, "base should not get MIME yaml marshaler")
+
+	_, ok = derived.Get(MIMEYAML).(*yaml.Codec)
+	require.True(t, ok, "derived should get MIME yaml marshaler")
+	_, err = derived.Lookup(MIMEXML)
+	require.ErrorIs(t, err, ErrUnsupportedMediaType)
+}
+
+func Test_Encoding_Concurrent(t *testing.T) {
+	registry := New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = registry.Register(MIMEXML, &xml.Codec{})
+				_ = registry.Delete(MIMEXML)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				r := httptest.NewRequest(http.MethodPost, "http://example.com", bytes.NewReader([]byte(`{"id":"foo"}`)))
+				r.Header.Set("Content-Type", MIMEJSON)
+				r.Header.Set("Accept", MIMEXML)
+				got := &TestMode{}
+				if err := registry.Bind(r, got); err != nil {
+					t.Error(err)
+				}
+				if err := registry.Render(httptest.NewRecorder(), r, got); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func Test_Encoding_Render_Stream(t *testing.T) {
+	registry := New()
+	require.NoError(t, registry.Register("application/x-0", &marshalers[0]))
+	require.NoError(t, registry.Register(MIMEPROTOBUF, &pro.Codec{}))
+	require.NoError(t, registry.Register(MIMEXML, &xml.Codec{}))
+	require.NoError(t, registry.Register(MIMEMSGPACK, &msgpack.Codec{}))
+	require.NoError(t, registry.Register(MIMEYAML, &yaml.Codec{}))
+	require.NoError(t, registry.Register(MIMETOML, &toml.Codec{}))
+
+	t.Run("encode error before committed", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		r.Header.Set("Accept", "application/x-0")
+		w := httptest.NewRecorder()
+
+		err := registry.Render(w, r, &TestMode{Id: "foo"})
+		require.Error(t, err)
+		require.Empty(t, w.Header().Get("Content-Type"))
+		require.Zero(t, w.Body.Len())
+		require.False(t, w.Flushed)
+	})
+	t.Run("large payload", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		w := httptest.NewRecorder()
+
+		want := make([]TestMode, 4096)
+		for i := range want {
+			want[i] = TestMode{Id: fmt.Sprint(i), Name: "bar"}
+		}
+		require.NoError(t, registry.Render(w, r, want))
+		require.Greater(t, w.Body.Len(), renderBufferSize)
+
+		var got []TestMode
+		require.NoError(t, registry.Get(MIMEJSON).Unmarshal(w.Body.Bytes(), &got))
+		require.Equal(t, want, got)
+	})
+	for _, mime := range []string{MIMEXML, MIMEMSGPACK, MIMEYAML, MIMETOML} {
+		t.Run(mime, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			r.Header.Set("Accept", mime)
+			w := httptest.NewRecorder()
+
+			want := &TestMode{Id: "foo", Name: "bar"}
+			require.NoError(t, registry.Render(w, r, want))
+
+			got := &TestMode{}
+			require.NoError(t, registry.Get(mime).Unmarshal(w.Body.Bytes(), got))
+			require.Equal(t, want, got)
+		})
+	}
+	t.Run(MIMEPROTOBUF, func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		r.Header.Set("Accept", MIMEPROTOBUF)
+		w := httptest.NewRecorder()
+
+		require.NoError(t, registry.Render(w, r, protoMessage))
+		require.Equal(t, "application/x-protobuf", w.Header().Get("Content-Type"))
+
+		got := &examplepb.ABitOfEverything{}
+		require.NoError(t, proto.Unmarshal(w.Body.Bytes(), got))
+		require.True(t, proto.Equal(protoMessage, got))
+	})
+	t.Run(MIMEPROTOBUF+" delimited", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		r.Header.Set("Accept", MIMEPROTOBUF+"; delimited=true")
+		w := httptest.NewRecorder()
+
+		require.NoError(t, registry.Render(w, r, protoMessage))
+		require.Equal(t, "application/x-protobuf; delimited=true", w.Header().Get("Content-Type"))
+
+		got := &examplepb.ABitOfEverything{}
+		require.NoError(t, protodelim.UnmarshalFrom(bytes.NewReader(w.Body.Bytes()), got))
+		require.True(t, proto.Equal(protoMessage, got))
+	})
+}
+
+func Test_Encoding_Render_ProtoText(t *testing.T) {
+	registry := New()
+	require.NoError(t, registry.Register(MIMEPROTOTEXT, &prototext.Codec{}))
+	require.NoError(t, registry.Register(MIMEPROTOTEXT2, &prototext.Codec{}))
+
+	for _, mime := range []string{MIMEPROTOTEXT, MIMEPROTOTEXT2} {
+		t.Run(mime, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			r.Header.Set("Accept", mime)
+			w := httptest.NewRecorder()
+
+			require.NoError(t, registry.Render(w, r, protoMessage))
+			require.Equal(t, mime+"; charset=utf-8", w.Header().Get("Content-Type"))
+
+			got := &examplepb.ABitOfEverything{}
+			require.NoError(t, registry.Get(mime).Unmarshal(w.Body.Bytes(), got))
+			require.True(t, proto.Equal(protoMessage, got))
+		})
+	}
+}
+
+func Test_Encoding_Bind_ProtoDelimited(t *testing.T) {
+	registry := New()
+	require.NoError(t, registry.Register(MIMEPROTOBUF, &pro.Codec{}))
+
+	var body bytes.Buffer
+	_, err := protodelim.MarshalTo(&body, protoMessage)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "http://example.com", &body)
+	r.Header.Set("Content-Type", MIMEPROTOBUF+"; delimited=true")
+	got := &examplepb.ABitOfEverything{}
+	require.NoError(t, registry.Bind(r, got))
+	require.True(t, proto.Equal(protoMessage, got))
+}
@@ -0,0 +1,84 @@
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedMediaType is reported in strict mode when the `Content-Type`
+	// is not registered, it is suitable for HTTP status 415 Unsupported Media Type.
+	ErrUnsupportedMediaType = errors.New("encoding: unsupported media type")
+	// ErrNotAcceptable is reported in strict mode when no registered MIME type is
+	// acceptable by the `Accept`, it is suitable for HTTP status 406 Not Acceptable.
+	ErrNotAcceptable = errors.New("encoding: not acceptable")
+)
+
+// MediaTypeError records a failed media type negotiation.
+// It wraps ErrUnsupportedMediaType or ErrNotAcceptable.
+type MediaTypeError struct {
+	// Err is ErrUnsupportedMediaType or ErrNotAcceptable.
+	Err error
+	// MediaType is the offending `Content-Type` or `Accept` header value.
+	MediaType string
+	// Supported is the sorted registered MIME types.
+	Supported []string
+}
+
+func (e *MediaTypeError) Error() string {
+	return fmt.Sprintf("%v: %q, supported: [%s]", e.Err, e.MediaType, strings.Join(e.Supported, ", "))
+}
+
+func (e *MediaTypeError) Unwrap() error { return e.Err }
+
+func (r *registry) newMediaTypeError(err error, mediaType string) *MediaTypeError {
+	return &MediaTypeError{
+		Err:       err,
+		MediaType: mediaType,
+		Supported: r.registered(),
+	}
+}
+
+var (
+	// ErrBodyTooLarge is reported when the request body exceeds the Limits,
+	// it is suitable for HTTP status 413 Content Too Large.
+	ErrBodyTooLarge = errors.New("encoding: request body too large")
+	// ErrReadTimeout is reported when reading the request body exceeds Limits.ReadTimeout,
+	// it is suitable for HTTP status 408 Request Timeout.
+	ErrReadTimeout = errors.New("encoding: request body read timeout")
+)
+
+// LimitError records the request body exceeds a limit of Limits.
+// It wraps ErrBodyTooLarge.
+type LimitError struct {
+	// Limit is the name of the exceeded limit, e.g. "MaxBodySize".
+	Limit string
+	// Max is the value of the exceeded limit.
+	Max int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v: exceeds %s(%d)", ErrBodyTooLarge, e.Limit, e.Max)
+}
+
+func (e *LimitError) Unwrap() error { return ErrBodyTooLarge }
+
+// ErrUnsupportedContentEncoding is reported when the request `Content-Encoding`
+// has no registered Compressor, it is suitable for HTTP status 415 Unsupported Media Type.
+var ErrUnsupportedContentEncoding = errors.New("encoding: unsupported content encoding")
+
+// ContentEncodingError records an unsupported request `Content-Encoding`.
+// It wraps ErrUnsupportedContentEncoding.
+type ContentEncodingError struct {
+	// ContentEncoding is the offending content coding.
+	ContentEncoding string
+	// Supported is the registered content codings.
+	Supported []string
+}
+
+func (e *ContentEncodingError) Error() string {
+	return fmt.Sprintf("%v: %q, supported: [%s]", ErrUnsupportedContentEncoding, e.ContentEncoding, strings.Join(e.Supported, ", "))
+}
+
+func (e *ContentEncodingError) Unwrap() error { return ErrUnsupportedContentEncoding }
@@ -1,9 +1,13 @@
 package form
 
 import (
+	"errors"
 	"io"
 	"net/url"
 	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/go-playground/form/v4"
 	"google.golang.org/protobuf/proto"
@@ -171,7 +175,34 @@ func (c *Codec) Decode(vs url.Values, v any) error {
 	if m, ok := rv.Interface().(proto.Message); ok {
 		return DecodeValues(m, vs)
 	}
-	return c.Decoder.Decode(v, vs)
+	return wrapDecodeErrors(c.Decoder.Decode(v, vs), vs)
+}
+
+// go-playground/form reports a field error like "Invalid Integer Value 'abc' Type 'int' Namespace 'page'".
+var decodeErrorRegexp = regexp.MustCompile(`Value '(.*)' Type '(.*)' Namespace`)
+
+// wrapDecodeErrors converts form.DecodeErrors into codec.FieldErrors, ordered by the field.
+func wrapDecodeErrors(err error, vs url.Values) error {
+	var decodeErrs form.DecodeErrors
+	if !errors.As(err, &decodeErrs) {
+		return err
+	}
+	fields := make([]string, 0, len(decodeErrs))
+	for field := range decodeErrs {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	errs := make(codec.FieldErrors, 0, len(decodeErrs))
+	for _, field := range fields {
+		e := decodeErrs[field]
+		fe := &codec.FieldError{Field: field, Value: strings.Join(vs[field], ","), Err: e}
+		if m := decodeErrorRegexp.FindStringSubmatch(e.Error()); m != nil {
+			fe.Value, fe.Type = m[1], m[2]
+		}
+		errs = append(errs, fe)
+	}
+	return errs.Err()
 }
 
 type MultipartCodec struct {
@@ -8,6 +8,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/things-go/encoding/codec"
 )
 
 type LoginRequest struct {
@@ -247,3 +249,16 @@ func Test_NewDecoder_Decode(t *testing.T) {
 		})
 	}
 }
+
+func TestCodec_DecodeFieldError(t *testing.T) {
+	type value struct {
+		N int `json:"n"`
+		M int `json:"m"`
+	}
+	err := New("json").Decode(url.Values{"n": {"abc"}, "m": {"def"}}, &value{})
+	var errs codec.FieldErrors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 2)
+	require.Equal(t, codec.FieldError{Field: "m", Value: "def", Type: "int", Err: errs[0].Err}, *errs[0])
+	require.Equal(t, codec.FieldError{Field: "n", Value: "abc", Type: "int", Err: errs[1].Err}, *errs[1])
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,18 +19,37 @@ import (
 	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/things-go/encoding/codec"
 )
 
 var errInvalidFormatMapKey = errors.New("invalid formatting for map key")
 
 // DecodeValues decode url value into proto message.
+// It decodes all the values, the failures are collected into codec.FieldErrors.
 func DecodeValues(msg proto.Message, values url.Values) error {
-	for k, v := range values {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var errs codec.FieldErrors
+	for _, k := range keys {
+		v := values[k]
 		if err := populateFieldValues(msg.ProtoReflect(), strings.Split(k, "."), v); err != nil {
-			return err
+			var fe *codec.FieldError
+			if !errors.As(err, &fe) {
+				fe = &codec.FieldError{Err: err}
+			}
+			fe.Field = k
+			if fe.Value == "" {
+				fe.Value = strings.Join(v, ",")
+			}
+			errs = append(errs, fe)
 		}
 	}
-	return nil
+	return errs.Err()
 }
 
 func populateFieldValues(v protoreflect.Message, fieldPath []string, values []string) error {
@@ -78,6 +98,18 @@ func populateFieldValues(v protoreflect.Message, fieldPath []string, values []st
 	return populateField(fd, v, values[0])
 }
 
+// fieldType returns the type name of the field, e.g. "int64" or "google.protobuf.Timestamp".
+func fieldType(fd protoreflect.FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return string(fd.Message().FullName())
+	case protoreflect.EnumKind:
+		return string(fd.Enum().FullName())
+	default:
+		return fd.Kind().String()
+	}
+}
+
 func getFieldDescriptor(v protoreflect.Message, fieldName string) protoreflect.FieldDescriptor {
 	var fields = v.Descriptor().Fields()
 	var fd = getDescriptorByFieldAndName(fields, fieldName)
@@ -115,7 +147,7 @@ func populateField(fd protoreflect.FieldDescriptor, v protoreflect.Message, valu
 	}
 	val, err := parseField(fd, value)
 	if err != nil {
-		return fmt.Errorf("parsing field %q: %w", fd.FullName().Name(), err)
+		return &codec.FieldError{Value: value, Type: fieldType(fd), Err: err}
 	}
 	v.Set(fd, val)
 	return nil
@@ -125,7 +157,7 @@ func populateRepeatedField(fd protoreflect.FieldDescriptor, list protoreflect.Li
 	for _, value := range values {
 		v, err := parseField(fd, value)
 		if err != nil {
-			return fmt.Errorf("parsing list %q: %w", fd.FullName().Name(), err)
+			return &codec.FieldError{Value: value, Type: "repeated " + fieldType(fd), Err: err}
 		}
 		list.Append(v)
 	}
@@ -143,11 +175,11 @@ func populateMapField(fd protoreflect.FieldDescriptor, mp protoreflect.Map, fiel
 	}
 	key, err := parseField(fd.MapKey(), keyName)
 	if err != nil {
-		return fmt.Errorf("parsing map key %q: %w", fd.FullName().Name(), err)
+		return &codec.FieldError{Value: keyName, Type: fieldType(fd.MapKey()), Err: err}
 	}
 	value, err := parseField(fd.MapValue(), values[vKey])
 	if err != nil {
-		return fmt.Errorf("parsing map value %q: %w", fd.FullName().Name(), err)
+		return &codec.FieldError{Value: values[vKey], Type: fieldType(fd.MapValue()), Err: err}
 	}
 	mp.Set(key.MapKey(), value)
 	return nil
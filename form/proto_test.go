@@ -1,6 +1,7 @@
 package form
 
 import (
+	"net/url"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -11,6 +12,7 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 
+	"github.com/things-go/encoding/codec"
 	"github.com/things-go/encoding/testdata/examplepb"
 )
 
@@ -74,3 +76,21 @@ func TestProto_Codec(t *testing.T) {
 		require.Empty(t, cmp.Diff(got, got, protocmp.Transform()))
 	})
 }
+
+func TestDecodeValues_FieldError(t *testing.T) {
+	err := DecodeValues(&examplepb.ABitOfEverything{}, url.Values{
+		"int64Value":   {"abc"},
+		"uint32_value": {"-1"},
+		"string_value": {"ok"},
+		"enum_value":   {"x"},
+	})
+	var errs codec.FieldErrors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 3)
+	require.Equal(t, "enum_value", errs[0].Field)
+	require.Equal(t, "int64Value", errs[1].Field)
+	require.Equal(t, "abc", errs[1].Value)
+	require.Equal(t, "int64", errs[1].Type)
+	require.Equal(t, "uint32_value", errs[2].Field)
+	require.Equal(t, "uint32", errs[2].Type)
+}
@@ -1,6 +1,8 @@
 package httpbody
 
 import (
+	"io"
+
 	"google.golang.org/genproto/googleapis/api/httpbody"
 
 	"github.com/things-go/encoding/codec"
@@ -32,3 +34,16 @@ func (h *HTTPBodyCodec) Marshal(v any) ([]byte, error) {
 	}
 	return h.Marshaler.Marshal(v)
 }
+
+// NewEncoder returns an Encoder which writes the body bytes into "w" if v is a
+// google.api.HttpBody message, otherwise it falls back to the default Marshaler's Encoder.
+func (h *HTTPBodyCodec) NewEncoder(w io.Writer) codec.Encoder {
+	enc := h.Marshaler.NewEncoder(w)
+	return codec.EncoderFunc(func(v any) error {
+		if httpBody, ok := v.(*httpbody.HttpBody); ok {
+			_, err := w.Write(httpBody.Data)
+			return err
+		}
+		return enc.Encode(v)
+	})
+}
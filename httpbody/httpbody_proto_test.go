@@ -52,3 +52,32 @@ func TestCodec_Marshal(t *testing.T) {
 		t.Errorf("Marshalled data not equal (%q, %q)", res, expected)
 	}
 }
+
+func TestCodec_NewEncoder(t *testing.T) {
+	m := HTTPBodyCodec{
+		&jsonpb.Codec{
+			MarshalOptions: protojson.MarshalOptions{
+				UseProtoNames: true,
+			},
+		},
+	}
+	expected := []byte("Some test")
+	message := &httpbody.HttpBody{
+		Data: expected,
+	}
+	var buf bytes.Buffer
+	if err := m.NewEncoder(&buf).Encode(message); err != nil {
+		t.Errorf("m.NewEncoder().Encode(%#v) failed with %v; want success", message, err)
+	}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("Encoded data not equal (%q, %q)", buf.Bytes(), expected)
+	}
+
+	buf.Reset()
+	if err := m.NewEncoder(&buf).Encode(map[string]string{"a": "b"}); err != nil {
+		t.Errorf("m.NewEncoder().Encode() failed with %v; want success", err)
+	}
+	if got, want := buf.String(), "{\"a\":\"b\"}\n"; got != want {
+		t.Errorf("Encoded data not equal (%q, %q)", got, want)
+	}
+}
@@ -0,0 +1,163 @@
+// Package wellknown implements the text forms of the protobuf well-known types
+// shared by the codecs which marshal the proto messages via protoreflect.
+package wellknown
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// FieldName returns the JSON name of the field, or the proto name if useProtoNames.
+func FieldName(fd protoreflect.FieldDescriptor, useProtoNames bool) string {
+	if useProtoNames {
+		return fd.TextName()
+	}
+	return fd.JSONName()
+}
+
+// Resolver returns the resolver, or protoregistry.GlobalTypes if nil.
+func Resolver(r protoregistry.MessageTypeResolver) protoregistry.MessageTypeResolver {
+	if r != nil {
+		return r
+	}
+	return protoregistry.GlobalTypes
+}
+
+// JoinPath joins the dotted field path with the name.
+func JoinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// IsWrapper reports whether the message is a wrapper type, e.g. google.protobuf.StringValue,
+// whose value is the field number 1.
+func IsWrapper(name protoreflect.FullName) bool {
+	switch name {
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		return true
+	}
+	return false
+}
+
+// Timestamp returns the time of the google.protobuf.Timestamp in UTC.
+func Timestamp(m protoreflect.Message) time.Time {
+	fields := m.Descriptor().Fields()
+	return time.Unix(m.Get(fields.ByNumber(1)).Int(), m.Get(fields.ByNumber(2)).Int()).UTC()
+}
+
+// SetTimestamp sets the google.protobuf.Timestamp to the time.
+func SetTimestamp(m protoreflect.Message, t time.Time) {
+	fields := m.Descriptor().Fields()
+	m.Set(fields.ByNumber(1), protoreflect.ValueOfInt64(t.Unix()))
+	m.Set(fields.ByNumber(2), protoreflect.ValueOfInt32(int32(t.Nanosecond())))
+}
+
+// FormatDuration returns the google.protobuf.Duration as the seconds with the "s" suffix, e.g. "1.5s".
+func FormatDuration(m protoreflect.Message) string {
+	fields := m.Descriptor().Fields()
+	seconds, nanos := m.Get(fields.ByNumber(1)).Int(), m.Get(fields.ByNumber(2)).Int()
+	sign := ""
+	if seconds < 0 || nanos < 0 {
+		sign, seconds, nanos = "-", -seconds, -nanos
+	}
+	text := strconv.FormatInt(seconds, 10)
+	if nanos != 0 {
+		text += strings.TrimRight(fmt.Sprintf(".%09d", nanos), "0")
+	}
+	return sign + text + "s"
+}
+
+// SetDuration parses the seconds with the "s" suffix, e.g. "-1.5s", into the google.protobuf.Duration.
+func SetDuration(m protoreflect.Message, text string) error {
+	s, ok := strings.CutSuffix(text, "s")
+	if !ok {
+		return errors.New("missing the unit suffix \"s\"")
+	}
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	whole, frac, _ := strings.Cut(s, ".")
+	seconds, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return err
+	}
+	var nanos int64
+	if frac != "" {
+		if len(frac) > 9 {
+			return errors.New("too many fractional digits")
+		}
+		if nanos, err = strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 32); err != nil {
+			return err
+		}
+	}
+	if neg {
+		seconds, nanos = -seconds, -nanos
+	}
+	fields := m.Descriptor().Fields()
+	m.Set(fields.ByNumber(1), protoreflect.ValueOfInt64(seconds))
+	m.Set(fields.ByNumber(2), protoreflect.ValueOfInt32(int32(nanos)))
+	return nil
+}
+
+// FormatFieldMask returns the paths of the google.protobuf.FieldMask separated by commas.
+func FormatFieldMask(m protoreflect.Message) string {
+	paths := m.Get(m.Descriptor().Fields().ByNumber(1)).List()
+	s := make([]string, paths.Len())
+	for i := range s {
+		s[i] = paths.Get(i).String()
+	}
+	return strings.Join(s, ",")
+}
+
+// SetFieldMask appends the comma-separated paths to the google.protobuf.FieldMask,
+// the empty paths are ignored.
+func SetFieldMask(m protoreflect.Message, text string) {
+	paths := m.Mutable(m.Descriptor().Fields().ByNumber(1)).List()
+	for _, p := range strings.Split(text, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths.Append(protoreflect.ValueOfString(p))
+		}
+	}
+}
+
+// UnpackAny returns the type URL and the resolved message of the google.protobuf.Any,
+// the message is nil if the type URL is empty.
+func UnpackAny(r protoregistry.MessageTypeResolver, m protoreflect.Message) (string, protoreflect.Message, error) {
+	fields := m.Descriptor().Fields()
+	typeURL := m.Get(fields.ByNumber(1)).String()
+	if typeURL == "" {
+		return "", nil, nil
+	}
+	mt, err := Resolver(r).FindMessageByURL(typeURL)
+	if err != nil {
+		return typeURL, nil, err
+	}
+	inner := mt.New()
+	if err = (proto.UnmarshalOptions{AllowPartial: true}).Unmarshal(m.Get(fields.ByNumber(2)).Bytes(), inner.Interface()); err != nil {
+		return typeURL, nil, err
+	}
+	return typeURL, inner, nil
+}
+
+// PackAny sets the google.protobuf.Any to the type URL and the message.
+func PackAny(m protoreflect.Message, typeURL string, inner protoreflect.Message) error {
+	b, err := proto.MarshalOptions{AllowPartial: true, Deterministic: true}.Marshal(inner.Interface())
+	if err != nil {
+		return err
+	}
+	fields := m.Descriptor().Fields()
+	m.Set(fields.ByNumber(1), protoreflect.ValueOfString(typeURL))
+	m.Set(fields.ByNumber(2), protoreflect.ValueOfBytes(b))
+	return nil
+}
@@ -0,0 +1,25 @@
+package wellknown
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestDuration(t *testing.T) {
+	for _, text := range []string{"0s", "1s", "1.5s", "-1.5s", "0.000000001s", "-0.1s"} {
+		d := &durationpb.Duration{}
+		if err := SetDuration(d.ProtoReflect(), text); err != nil {
+			t.Errorf("SetDuration(%q) failed, err = %v", text, err)
+			continue
+		}
+		if got := FormatDuration(d.ProtoReflect()); got != text {
+			t.Errorf("FormatDuration(_) failed, got = %q; want %q", got, text)
+		}
+	}
+	for _, text := range []string{"1", "1.5", "a.5s", "1.0000000001s"} {
+		if err := SetDuration((&durationpb.Duration{}).ProtoReflect(), text); err == nil {
+			t.Errorf("SetDuration(%q) should fail", text)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package json
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
+	"strconv"
+	"strings"
 
 	"github.com/things-go/encoding/codec"
 )
@@ -12,8 +15,12 @@ import (
 // Although it is generally faster for simple proto messages than JSONPb,
 // it does not support advanced features of protobuf, e.g. map, oneof, ....
 //
-// The NewEncoder and NewDecoder types return *json.Encoder and
-// *json.Decoder respectively.
+// The NewEncoder returns *json.Encoder, and the NewDecoder returns a DecoderWrapper,
+// which embeds the *json.Decoder, so its methods (e.g. More, Token) can be used and the
+// *json.Decoder is available as the Decoder field.
+// NOTE: the NewDecoder no longer returns *json.Decoder, a type assertion to *json.Decoder
+// fails, assert DecoderWrapper instead.
+// The decode failures are reported as *codec.FieldError.
 type Codec struct {
 	// UseNumber causes the Decoder to unmarshal a number into an any as a
 	// Number instead of as a float64.
@@ -32,7 +39,7 @@ func (*Codec) Marshal(v any) ([]byte, error) {
 	return json.Marshal(v)
 }
 func (*Codec) Unmarshal(data []byte, v any) error {
-	return json.Unmarshal(data, v)
+	return WrapError(json.Unmarshal(data, v))
 }
 func (c *Codec) NewDecoder(r io.Reader) codec.Decoder {
 	decoder := json.NewDecoder(r)
@@ -42,11 +49,54 @@ func (c *Codec) NewDecoder(r io.Reader) codec.Decoder {
 	if c.DisallowUnknownFields {
 		decoder.DisallowUnknownFields()
 	}
-	return decoder
+	return DecoderWrapper{decoder}
 }
 func (c *Codec) NewEncoder(w io.Writer) codec.Encoder {
 	return json.NewEncoder(w)
 }
+
+// Delimiter for newline encoded JSON streams.
 func (c *Codec) Delimiter() []byte {
 	return []byte("\n")
 }
+
+// DecoderWrapper is a wrapper around a *json.Decoder that reports
+// the decode failures as *codec.FieldError.
+type DecoderWrapper struct {
+	*json.Decoder
+}
+
+// Decode wraps the embedded decoder's Decode method.
+func (d DecoderWrapper) Decode(v any) error {
+	return WrapError(d.Decoder.Decode(v))
+}
+
+// WrapError converts the decode failures of "encoding/json" into *codec.FieldError,
+// other errors (e.g. the reader's error) are returned as is.
+func WrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		fe := &codec.FieldError{Field: typeErr.Field, Err: err}
+		if typeErr.Type != nil {
+			fe.Type = typeErr.Type.String()
+		}
+		return fe
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return &codec.FieldError{Err: err}
+	case strings.HasPrefix(err.Error(), unknownFieldPrefix):
+		field, e := strconv.Unquote(strings.TrimPrefix(err.Error(), unknownFieldPrefix))
+		if e != nil {
+			field = ""
+		}
+		return &codec.FieldError{Field: field, Err: err}
+	}
+	return err
+}
+
+// unknownFieldPrefix is the error prefix of an unknown field with DisallowUnknownFields.
+const unknownFieldPrefix = "json: unknown field "
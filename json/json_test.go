@@ -3,6 +3,8 @@ package json
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
 	"reflect"
 	"strings"
 	"testing"
@@ -14,6 +16,7 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 
+	"github.com/things-go/encoding/codec"
 	"github.com/things-go/encoding/testdata/examplepb"
 )
 
@@ -186,6 +189,13 @@ func TestCodec_Decoder(t *testing.T) {
 	if diff := cmp.Diff(got, want, protocmp.Transform()); diff != "" {
 		t.Errorf("got = %v; want = %v", got, want)
 	}
+	w, ok := dec.(DecoderWrapper)
+	if !ok || w.Decoder == nil {
+		t.Fatalf("m.NewDecoder() = %T; want DecoderWrapper of *json.Decoder", dec)
+	}
+	if w.More() {
+		t.Errorf("w.More() = true; want false at the end of the input")
+	}
 }
 
 func TestCodec_Delimiter(t *testing.T) {
@@ -283,3 +293,115 @@ var (
 		},
 	}
 )
+
+func TestCodec_DecodeFieldError(t *testing.T) {
+	type sub struct {
+		N int `json:"n"`
+	}
+	type value struct {
+		Sub sub `json:"sub"`
+	}
+	tests := []struct {
+		name      string
+		codec     *Codec
+		data      string
+		wantField string
+		wantType  string
+	}{
+		{"type", &Codec{}, `{"sub":{"n":"abc"}}`, "sub.n", "int"},
+		{"unknown field", &Codec{DisallowUnknownFields: true}, `{"x":1}`, "x", ""},
+		{"syntax", &Codec{}, `{"sub":`, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := []error{tt.codec.NewDecoder(strings.NewReader(tt.data)).Decode(&value{})}
+			if !tt.codec.DisallowUnknownFields { // Unmarshal always allows unknown fields.
+				errs = append(errs, tt.codec.Unmarshal([]byte(tt.data), &value{}))
+			}
+			for _, err := range errs {
+				var fe *codec.FieldError
+				if !errors.As(err, &fe) {
+					t.Fatalf("error = %v, want *codec.FieldError", err)
+				}
+				if fe.Field != tt.wantField || fe.Type != tt.wantType {
+					t.Errorf("FieldError = %+v, want field %q and type %q", fe, tt.wantField, tt.wantType)
+				}
+			}
+		})
+	}
+}
+
+func TestCodec_Stream(t *testing.T) {
+	type value struct {
+		N int `json:"n"`
+	}
+	m := Codec{DisallowUnknownFields: true}
+
+	var buf bytes.Buffer
+	enc := m.NewStreamEncoder(&buf)
+	if err := enc.Close(); err != nil {
+		t.Fatalf("enc.Close() failed with %v; want success", err)
+	}
+	if want := "[]\n"; buf.String() != want {
+		t.Errorf("empty stream got = %q; want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	enc = m.NewStreamEncoder(&buf)
+	for i := 1; i <= 3; i++ {
+		if err := enc.Encode(&value{N: i}); err != nil {
+			t.Fatalf("enc.Encode() failed with %v; want success", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("enc.Close() failed with %v; want success", err)
+	}
+	if want := `[{"n":1},{"n":2},{"n":3}]` + "\n"; buf.String() != want {
+		t.Errorf("stream got = %q; want %q", buf.String(), want)
+	}
+
+	var got []int
+	codec.Iter[*value](m.NewStreamDecoder(&buf))(func(v *value, err error) bool {
+		if err != nil {
+			t.Fatalf("Decode() failed with %v; want success", err)
+		}
+		got = append(got, v.N)
+		return true
+	})
+	if diff := cmp.Diff(got, []int{1, 2, 3}); diff != "" {
+		t.Error(diff)
+	}
+
+	for _, data := range []string{"", " "} {
+		if err := m.NewStreamDecoder(strings.NewReader(data)).Decode(&value{}); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("Decode(%q) error = %v; want %v", data, err, io.ErrUnexpectedEOF)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		data      string
+		wantField string
+	}{
+		{"not array", `{"n":1}`, ""},
+		{"unknown field", `[{"n":1},{"x":1}]`, "[1].x"},
+		{"type", `[{"n":"abc"}]`, "[0].n"},
+		{"unfinished", `[{"n":1}`, "[1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := m.NewStreamDecoder(strings.NewReader(tt.data))
+			var err error
+			for err == nil {
+				err = dec.Decode(&value{})
+			}
+			var fe *codec.FieldError
+			if !errors.As(err, &fe) {
+				t.Fatalf("error = %v, want *codec.FieldError", err)
+			}
+			if fe.Field != tt.wantField {
+				t.Errorf("FieldError.Field = %q, want %q", fe.Field, tt.wantField)
+			}
+		})
+	}
+}
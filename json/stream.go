@@ -0,0 +1,125 @@
+package json
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/things-go/encoding/codec"
+)
+
+// NewStreamEncoder returns a StreamEncoder which writes the records as a JSON array into "w",
+// it implements codec.StreamMarshaler.
+func (c *Codec) NewStreamEncoder(w io.Writer) codec.StreamEncoder {
+	return NewArrayEncoder(w, c.Marshal)
+}
+
+// NewStreamDecoder returns a Decoder which reads the elements of a JSON array from "r",
+// it implements codec.StreamMarshaler.
+func (c *Codec) NewStreamDecoder(r io.Reader) codec.Decoder {
+	d := c.NewDecoder(r).(DecoderWrapper)
+	return NewArrayDecoder(d.Decoder, func(d *json.Decoder, v any) error {
+		return WrapError(d.Decode(v))
+	})
+}
+
+// ArrayEncoder writes the records as the elements of a JSON array.
+type ArrayEncoder struct {
+	w       io.Writer
+	marshal func(v any) ([]byte, error)
+	n       int
+}
+
+// NewArrayEncoder returns an ArrayEncoder which writes into "w",
+// each record is marshaled by marshal.
+func NewArrayEncoder(w io.Writer, marshal func(v any) ([]byte, error)) *ArrayEncoder {
+	return &ArrayEncoder{w: w, marshal: marshal}
+}
+
+// Encode writes v as the next element.
+func (e *ArrayEncoder) Encode(v any) error {
+	b, err := e.marshal(v)
+	if err != nil {
+		return err
+	}
+	sep := []byte{','}
+	if e.n == 0 {
+		sep = []byte{'['}
+	}
+	if _, err = e.w.Write(append(sep, b...)); err != nil {
+		return err
+	}
+	e.n++
+	return nil
+}
+
+// Close writes the end of the JSON array.
+func (e *ArrayEncoder) Close() error {
+	end := "]\n"
+	if e.n == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// ArrayDecoder reads the elements of a JSON array one at a time.
+type ArrayDecoder struct {
+	d       *json.Decoder
+	decode  func(d *json.Decoder, v any) error
+	n       int
+	started bool
+	done    bool
+}
+
+// NewArrayDecoder returns an ArrayDecoder which reads from d,
+// each element is decoded by decode.
+func NewArrayDecoder(d *json.Decoder, decode func(d *json.Decoder, v any) error) *ArrayDecoder {
+	return &ArrayDecoder{d: d, decode: decode}
+}
+
+// Decode decodes the next element into v, it returns io.EOF after the last element.
+// An empty input is not a JSON array, it is reported as io.ErrUnexpectedEOF.
+// The decode failures are reported as *codec.FieldError with the element index
+// in the Field, e.g. "[3].name".
+func (a *ArrayDecoder) Decode(v any) error {
+	if a.done {
+		return io.EOF
+	}
+	if !a.started {
+		tok, err := a.d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return WrapError(err)
+		}
+		if tok != json.Delim('[') {
+			return &codec.FieldError{Err: fmt.Errorf("json: expect the start of an array, got %v", tok)}
+		}
+		a.started = true
+	}
+	if !a.d.More() {
+		if _, err := a.d.Token(); err != nil {
+			return WrapError(err)
+		}
+		a.done = true
+		return io.EOF
+	}
+	index := fmt.Sprintf("[%d]", a.n)
+	a.n++
+	if err := a.decode(a.d, v); err != nil {
+		var fe *codec.FieldError
+		if !errors.As(err, &fe) {
+			return &codec.FieldError{Field: index, Err: err}
+		}
+		if fe.Field == "" {
+			fe.Field = index
+		} else {
+			fe.Field = index + "." + fe.Field
+		}
+		return err
+	}
+	return nil
+}
@@ -3,15 +3,19 @@ package jsonpb
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/things-go/encoding/codec"
+	jsoncodec "github.com/things-go/encoding/json"
 )
 
 // Codec is a Marshaler which marshals/unmarshals into/from JSON
@@ -20,6 +24,7 @@ import (
 //
 // The NewDecoder method returns a DecoderWrapper, so the underlying
 // *json.Decoder methods can be used.
+// The decode failures are reported as *codec.FieldError.
 type Codec struct {
 	protojson.MarshalOptions
 	protojson.UnmarshalOptions
@@ -169,7 +174,7 @@ func (c *Codec) marshalNonProtoField(v any) ([]byte, error) {
 
 // Unmarshal unmarshals JSON "data" into "v"
 func (c *Codec) Unmarshal(data []byte, v any) error {
-	return unmarshalJSONPb(data, c.UnmarshalOptions, v)
+	return wrapError(unmarshalJSONPb(data, c.UnmarshalOptions, v))
 }
 
 // NewDecoder returns a Decoder which reads JSON stream from "r".
@@ -191,7 +196,7 @@ type DecoderWrapper struct {
 // Decode wraps the embedded decoder's Decode method to support
 // protos using a jsonpb.Unmarshaler.
 func (d DecoderWrapper) Decode(v any) error {
-	return decodeJSONPb(d.Decoder, d.UnmarshalOptions, v)
+	return wrapError(decodeJSONPb(d.Decoder, d.UnmarshalOptions, v))
 }
 
 // NewEncoder returns an Encoder which writes JSON stream into "w".
@@ -341,6 +346,20 @@ func (c *Codec) Delimiter() []byte {
 	return []byte("\n")
 }
 
+// NewStreamEncoder returns a StreamEncoder which writes the records as a JSON array into "w",
+// it implements codec.StreamMarshaler.
+func (c *Codec) NewStreamEncoder(w io.Writer) codec.StreamEncoder {
+	return jsoncodec.NewArrayEncoder(w, c.Marshal)
+}
+
+// NewStreamDecoder returns a Decoder which reads the elements of a JSON array from "r",
+// it implements codec.StreamMarshaler.
+func (c *Codec) NewStreamDecoder(r io.Reader) codec.Decoder {
+	return jsoncodec.NewArrayDecoder(json.NewDecoder(r), func(d *json.Decoder, v any) error {
+		return wrapError(decodeJSONPb(d, c.UnmarshalOptions, v))
+	})
+}
+
 var (
 	convFromType = map[reflect.Kind]reflect.Value{
 		reflect.String:  reflect.ValueOf(codec.String),
@@ -354,3 +373,43 @@ var (
 		reflect.Slice:   reflect.ValueOf(codec.Bytes),
 	}
 )
+
+var (
+	// protojson reports a field error like `proto: (line 1:8): invalid value for int64 field id: "abc"`.
+	protoInvalidValueRegexp = regexp.MustCompile(`invalid value for (\S+) field (\S+): (.*)$`)
+	// protojson reports an unknown field error like `proto: (line 1:2): unknown field "foo"`.
+	protoUnknownFieldRegexp = regexp.MustCompile(`unknown field "?([^"\s]+)"?$`)
+)
+
+// wrapError converts the decode failures into *codec.FieldError,
+// other errors (e.g. the reader's error) are returned as is.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if codec.AsFieldErrors(err) != nil {
+		return err
+	}
+	if e := jsoncodec.WrapError(err); e != err {
+		return e
+	}
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		return &codec.FieldError{Value: numErr.Num, Err: err}
+	}
+	msg := err.Error()
+	if !strings.HasPrefix(msg, "proto:") {
+		return err
+	}
+	if m := protoInvalidValueRegexp.FindStringSubmatch(msg); m != nil {
+		value, e := strconv.Unquote(m[3])
+		if e != nil {
+			value = m[3]
+		}
+		return &codec.FieldError{Field: m[2], Value: value, Type: m[1], Err: err}
+	}
+	if m := protoUnknownFieldRegexp.FindStringSubmatch(msg); m != nil {
+		return &codec.FieldError{Field: m[1], Err: err}
+	}
+	return &codec.FieldError{Err: err}
+}
@@ -2,6 +2,7 @@ package jsonpb
 
 import (
 	"bytes"
+	"errors"
 	"reflect"
 	"strconv"
 	"strings"
@@ -17,6 +18,7 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 
+	"github.com/things-go/encoding/codec"
 	"github.com/things-go/encoding/testdata/examplepb"
 )
 
@@ -995,3 +997,71 @@ var builtinFieldFixtures = []struct {
 		json: "1",
 	},
 }
+
+func TestCodec_DecodeFieldError(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want codec.FieldError
+	}{
+		{"invalid value", `{"int64Value":"abc"}`, codec.FieldError{Field: "int64Value", Value: "abc", Type: "int64"}},
+		{"unknown field", `{"foo":"abc"}`, codec.FieldError{Field: "foo"}},
+		{"syntax", `{"foo":`, codec.FieldError{}},
+	}
+	m := Codec{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, err := range []error{
+				m.Unmarshal([]byte(tt.data), &examplepb.ABitOfEverything{}),
+				m.NewDecoder(strings.NewReader(tt.data)).Decode(&examplepb.ABitOfEverything{}),
+			} {
+				var fe *codec.FieldError
+				if !errors.As(err, &fe) {
+					t.Fatalf("error = %v, want *codec.FieldError", err)
+				}
+				if fe.Field != tt.want.Field || fe.Value != tt.want.Value || fe.Type != tt.want.Type {
+					t.Errorf("FieldError = %+v, want %+v", fe, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCodec_Stream(t *testing.T) {
+	m := Codec{}
+
+	var buf bytes.Buffer
+	enc := m.NewStreamEncoder(&buf)
+	for _, id := range []string{"foo", "bar"} {
+		if err := enc.Encode(&examplepb.SimpleMessage{Id: id}); err != nil {
+			t.Fatalf("enc.Encode() failed with %v; want success", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("enc.Close() failed with %v; want success", err)
+	}
+
+	var got []*examplepb.SimpleMessage
+	codec.Iter[*examplepb.SimpleMessage](m.NewStreamDecoder(&buf))(func(v *examplepb.SimpleMessage, err error) bool {
+		if err != nil {
+			t.Fatalf("Decode() failed with %v; want success", err)
+		}
+		got = append(got, v)
+		return true
+	})
+	want := []*examplepb.SimpleMessage{{Id: "foo"}, {Id: "bar"}}
+	if diff := cmp.Diff(got, want, protocmp.Transform()); diff != "" {
+		t.Error(diff)
+	}
+
+	err := m.NewStreamDecoder(strings.NewReader(`[{"id":"foo"},{"foo":"bar"}]`)).Decode(&examplepb.SimpleMessage{})
+	if err != nil {
+		t.Fatalf("Decode() failed with %v; want success", err)
+	}
+	dec := m.NewStreamDecoder(strings.NewReader(`[{"id":"foo"},{"foo":"bar"}]`))
+	_ = dec.Decode(&examplepb.SimpleMessage{})
+	var fe *codec.FieldError
+	if err = dec.Decode(&examplepb.SimpleMessage{}); !errors.As(err, &fe) || fe.Field != "[1].foo" {
+		t.Errorf("Decode() error = %v; want *codec.FieldError of [1].foo", err)
+	}
+}
@@ -0,0 +1,189 @@
+package encoding
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+// Limits limits the request body read by Encoding.Bind.
+// The zero value of each field means no limit,
+// except MaxMultipartMemory which defaults to 32MiB.
+type Limits struct {
+	// MaxBodySize is the maximum bytes of the request body.
+	MaxBodySize int64
+	// ReadTimeout is the maximum duration to read the request body.
+	// NOTE: it is checked between reads, a blocking read can only be
+	// interrupted by the server's read deadline.
+	ReadTimeout time.Duration
+	// MaxMultipartMemory is the maximum bytes of a multipart form stored in memory,
+	// the rest of the file parts are stored on disk in temporary files.
+	MaxMultipartMemory int64
+	// MaxMultipartParts is the maximum number of multipart form parts, values and files,
+	// the parts are counted while they are read.
+	MaxMultipartParts int
+	// MaxFormKeys is the maximum number of form values, i.e. the key-value pairs of the
+	// `application/x-www-form-urlencoded` body, counted before it is parsed,
+	// or the value parts of the multipart form, counted while they are read.
+	MaxFormKeys int
+	// MaxFileSize is the maximum bytes of each multipart file, checked while it is read,
+	// exceeding it reports *LimitError.
+	MaxFileSize int64
+	// AllowedFileTypes is the allowed media ranges of the multipart files' `Content-Type`,
+	// e.g. "image/png" or "image/*", others report ErrUnsupportedMediaType in *MediaTypeError.
+	// Empty means any.
+	AllowedFileTypes []string
+}
+
+func (l *Limits) multipartMemory() int64 {
+	if l.MaxMultipartMemory > 0 {
+		return l.MaxMultipartMemory
+	}
+	return defaultMemory
+}
+
+// checkFormKeys checks the number of form values.
+func (l *Limits) checkFormKeys(n int) error {
+	if l.MaxFormKeys > 0 && n > l.MaxFormKeys {
+		return &LimitError{Limit: "MaxFormKeys", Max: int64(l.MaxFormKeys)}
+	}
+	return nil
+}
+
+// parseMultipartForm parses the multipart form like http.Request.ParseMultipartForm,
+// but the parts are counted while they are read, so the form exceeding the limits is
+// rejected without reading the rest of it. The parts are copied through a pipe into
+// multipart.Reader.ReadForm, which stores the files like http.Request.ParseMultipartForm.
+func (l *Limits) parseMultipartForm(req *http.Request) error {
+	if req.MultipartForm != nil {
+		return nil
+	}
+	mr, err := req.MultipartReader()
+	if err != nil {
+		return err
+	}
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	done := make(chan error, 1)
+	go func() {
+		err := l.copyParts(mw, mr)
+		pw.CloseWithError(err)
+		done <- err
+	}()
+	form, err := multipart.NewReader(pr, mw.Boundary()).ReadForm(l.multipartMemory())
+	pr.Close() // stops copying the parts if the form is failed.
+	if copyErr := <-done; copyErr != nil && !errors.Is(copyErr, io.ErrClosedPipe) {
+		if form != nil {
+			form.RemoveAll() // nolint: errcheck
+		}
+		return copyErr
+	}
+	if err != nil {
+		return err
+	}
+	req.MultipartForm = form
+	return nil
+}
+
+// copyParts copies the parts of mr into mw, until a part exceeds the limits.
+func (l *Limits) copyParts(mw *multipart.Writer, mr *multipart.Reader) error {
+	parts, values := 0, 0
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return mw.Close()
+		}
+		if err != nil {
+			return err
+		}
+		if parts++; l.MaxMultipartParts > 0 && parts > l.MaxMultipartParts {
+			return &LimitError{Limit: "MaxMultipartParts", Max: int64(l.MaxMultipartParts)}
+		}
+		w, err := mw.CreatePart(p.Header)
+		if err != nil {
+			return err
+		}
+		if p.FileName() != "" {
+			err = l.copyFile(w, p)
+		} else {
+			values++
+			if err = l.checkFormKeys(values); err == nil {
+				_, err = io.Copy(w, p)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// limitBody replaces the request body with a limitedBody if needed.
+func (l *Limits) limitBody(req *http.Request) *limitedBody {
+	if req.Body == nil || req.Body == http.NoBody || (l.MaxBodySize <= 0 && l.ReadTimeout <= 0) {
+		return nil
+	}
+	body := &limitedBody{
+		rc:  req.Body,
+		max: l.MaxBodySize,
+		n:   l.MaxBodySize,
+	}
+	if l.ReadTimeout > 0 {
+		body.deadline = time.Now().Add(l.ReadTimeout)
+	}
+	req.Body = body
+	return body
+}
+
+// limitedBody is like http.MaxBytesReader, but reports *LimitError or ErrReadTimeout,
+// and the error is sticky, so it can be checked after the codec wrapped or
+// discarded the read error.
+type limitedBody struct {
+	rc       io.ReadCloser
+	max      int64 // max bytes, <= 0 means no limit.
+	n        int64 // remaining bytes.
+	deadline time.Time
+	err      error // sticky error.
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if !l.deadline.IsZero() && time.Now().After(l.deadline) {
+		l.err = ErrReadTimeout
+		return 0, l.err
+	}
+	if l.max <= 0 {
+		return l.rc.Read(p)
+	}
+	// read one more byte to detect whether exceeds the limit.
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.rc.Read(p)
+	if int64(n) <= l.n {
+		l.n -= int64(n)
+		return n, err
+	}
+	n = int(l.n)
+	l.n = 0
+	l.err = &LimitError{Limit: "MaxBodySize", Max: l.max}
+	return n, l.err
+}
+
+func (l *limitedBody) Close() error {
+	return l.rc.Close()
+}
+
+// check returns the sticky error if any, otherwise err.
+func (l *limitedBody) check(err error) error {
+	if l != nil && l.err != nil && err != nil {
+		return l.err
+	}
+	return err
+}
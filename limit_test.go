@@ -0,0 +1,119 @@
+package encoding
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type slowReader struct {
+	r     io.Reader
+	delay time.Duration
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return s.r.Read(p)
+}
+
+func Test_Encoding_Bind_Limits(t *testing.T) {
+	newRequest := func(contentType string, body io.Reader) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "http://example.com", body)
+		r.Header.Set("Content-Type", contentType)
+		return r
+	}
+
+	t.Run("no limit", func(t *testing.T) {
+		registry := New()
+		got := TestMode{}
+		err := registry.Bind(newRequest(MIMEJSON, strings.NewReader(`{"id":"foo","name":"bar"}`)), &got)
+		require.NoError(t, err)
+		require.Equal(t, "foo", got.Id)
+	})
+	t.Run("max body size", func(t *testing.T) {
+		registry := New().SetLimits(Limits{MaxBodySize: 16})
+		got := TestMode{}
+		err := registry.Bind(newRequest(MIMEJSON, strings.NewReader(`{"id":"foo","name":"bar"}`)), &got)
+		require.ErrorIs(t, err, ErrBodyTooLarge)
+		var limitErr *LimitError
+		require.ErrorAs(t, err, &limitErr)
+		require.Equal(t, "MaxBodySize", limitErr.Limit)
+		require.Equal(t, int64(16), limitErr.Max)
+
+		err = registry.Bind(newRequest(MIMEJSON, strings.NewReader(`{"id":"foo"}`)), &got)
+		require.NoError(t, err)
+	})
+	t.Run("per MIME limits", func(t *testing.T) {
+		registry := New().
+			SetLimits(Limits{MaxBodySize: 16}).
+			SetMIMELimits(MIMEJSON, Limits{MaxBodySize: 1024})
+		got := TestMode{}
+		err := registry.Bind(newRequest(MIMEJSON, strings.NewReader(`{"id":"foo","name":"bar"}`)), &got)
+		require.NoError(t, err)
+		err = registry.Bind(newRequest(MIMEPOSTForm, strings.NewReader(`id=foo&name=barbarbar`)), &got)
+		require.ErrorIs(t, err, ErrBodyTooLarge)
+	})
+	t.Run("read timeout", func(t *testing.T) {
+		registry := New().SetLimits(Limits{ReadTimeout: 10 * time.Millisecond})
+		body := &slowReader{r: strings.NewReader(`{"id":"foo","name":"bar"}`), delay: 5 * time.Millisecond}
+		got := TestMode{}
+		err := registry.Bind(newRequest(MIMEJSON, body), &got)
+		require.ErrorIs(t, err, ErrReadTimeout)
+	})
+	t.Run("max form keys", func(t *testing.T) {
+		registry := New().SetLimits(Limits{MaxFormKeys: 2})
+		got := TestMode{}
+		err := registry.Bind(newRequest(MIMEPOSTForm, strings.NewReader(`id=foo&name=bar`)), &got)
+		require.NoError(t, err)
+		require.Equal(t, TestMode{Id: "foo", Name: "bar"}, got)
+
+		err = registry.Bind(newRequest(MIMEPOSTForm, strings.NewReader(`id=foo&name=bar&a=1`)), &got)
+		require.ErrorIs(t, err, ErrBodyTooLarge)
+		err = registry.Bind(newRequest(MIMEPOSTForm, strings.NewReader(`id=foo&id=bar&id=baz`)), &got)
+		require.ErrorIs(t, err, ErrBodyTooLarge)
+	})
+	t.Run("max multipart parts", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		require.NoError(t, mw.WriteField("id", "foo"))
+		require.NoError(t, mw.WriteField("name", "bar"))
+		fw, err := mw.CreateFormFile("file", "file.txt")
+		require.NoError(t, err)
+		_, err = fw.Write([]byte("hello"))
+		require.NoError(t, err)
+		require.NoError(t, mw.Close())
+
+		registry := New().SetLimits(Limits{MaxMultipartParts: 3})
+		got := TestMode{}
+		err = registry.Bind(newRequest(mw.FormDataContentType(), bytes.NewReader(body.Bytes())), &got)
+		require.NoError(t, err)
+		require.Equal(t, TestMode{Id: "foo", Name: "bar"}, got)
+
+		registry = New().SetLimits(Limits{MaxMultipartParts: 2})
+		err = registry.Bind(newRequest(mw.FormDataContentType(), bytes.NewReader(body.Bytes())), &got)
+		require.ErrorIs(t, err, ErrBodyTooLarge)
+
+		// the parts after the limit are not read.
+		unread := io.MultiReader(bytes.NewReader(body.Bytes()), iotest.ErrReader(errors.New("read after the limit")))
+		err = registry.Bind(newRequest(mw.FormDataContentType(), unread), &got)
+		require.ErrorIs(t, err, ErrBodyTooLarge)
+
+		registry = New().SetLimits(Limits{MaxFormKeys: 1})
+		err = registry.Bind(newRequest(mw.FormDataContentType(), bytes.NewReader(body.Bytes())), &got)
+		var limitErr *LimitError
+		require.ErrorAs(t, err, &limitErr)
+		require.Equal(t, "MaxFormKeys", limitErr.Limit)
+	})
+}
@@ -2,15 +2,119 @@ package msgpack
 
 import (
 	"bytes"
+	"errors"
 	"io"
+	"reflect"
 
 	msgpack "github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
 
 	"github.com/things-go/encoding/codec"
 )
 
-// Codec is a Codec implementation with xml.
-type Codec struct{}
+// Codec is a Codec implementation with msgpack.
+// Other than the proto messages, the values are marshaled with the reflection of
+// "github.com/ugorji/go/codec".
+// The proto messages are marshaled via protoreflect as the maps of the populated fields
+// keyed by the JSON names, or the proto names if Codec.UseProtoNames, in the field order.
+// The map fields are the maps ordered by the keys, an enum is the name, or the number if
+// Codec.UseEnumNumbers or the number has no name, and bytes is the bin type.
+// Timestamp is the timestamp extension type, Duration is the seconds with the "s" suffix,
+// e.g. "1.5s", a wrapper is the value, FieldMask is the comma-separated paths, Struct,
+// ListValue and Value are the maps, arrays and values, and Any is the map of the "@type"
+// key of the type URL and the fields of the resolved message, or the "value" key of a
+// well-known type.
+// The proto messages are always written in the new spec, the str and bin types and the
+// timestamp extension, regardless of Codec.Handle, whose options apply to the other values.
+// The encoders and decoders share the Codec.Handle, which is configured by the chainable
+// methods and the extensions registered before the first use, the Codec without a Handle
+// uses a shared handle of the defaults until it is configured.
+// The NewEncoder returns an *EncoderWrapper of *msgpack.Encoder,
+// and the NewDecoder returns a DecoderWrapper of *msgpack.Decoder.
+// The decode failures are reported as *codec.FieldError, whose Field is empty since the
+// msgpack decoder does not report the fields, except the failures of the proto messages.
+type Codec struct {
+	// Handle is the reusable handle of the encoders and decoders, a shared handle of
+	// the defaults is used if nil, and a new one is allocated when configured.
+	// It must not be modified after the first use.
+	Handle *msgpack.MsgpackHandle
+	// UseProtoNames uses the proto field names instead of the JSON names as the map keys.
+	UseProtoNames bool
+	// UseEnumNumbers writes the enums as the numbers instead of the names.
+	UseEnumNumbers bool
+	// DiscardUnknown ignores the unknown fields of the proto messages,
+	// otherwise they are reported as *codec.FieldError.
+	DiscardUnknown bool
+	// Resolver resolves the message types of Any, default protoregistry.GlobalTypes.
+	Resolver protoregistry.MessageTypeResolver
+}
+
+// New returns a new Codec with a handle of the defaults, which can be configured.
+func New() *Codec {
+	return &Codec{Handle: new(msgpack.MsgpackHandle)}
+}
+
+// defaultHandle is the handle of the Codec without the Handle.
+var defaultHandle = new(msgpack.MsgpackHandle)
+
+func (c *Codec) handle() *msgpack.MsgpackHandle {
+	if c.Handle != nil {
+		return c.Handle
+	}
+	return defaultHandle
+}
+
+// mutableHandle returns the Codec.Handle, it is allocated if nil,
+// so that the shared handle is never modified.
+func (c *Codec) mutableHandle() *msgpack.MsgpackHandle {
+	if c.Handle == nil {
+		c.Handle = new(msgpack.MsgpackHandle)
+	}
+	return c.Handle
+}
+
+// SetCanonical sorts the map keys, so the same values are always the same bytes,
+// e.g. for hashing.
+func (c *Codec) SetCanonical(canonical bool) *Codec {
+	c.mutableHandle().Canonical = canonical
+	return c
+}
+
+// SetWriteExt writes the new spec of msgpack, the str and bin types and the
+// extension types, instead of the raw type of the old spec.
+func (c *Codec) SetWriteExt(writeExt bool) *Codec {
+	c.mutableHandle().WriteExt = writeExt
+	return c
+}
+
+// SetRawToString decodes the raw bytes of the old spec into string
+// instead of []byte when the target is an interface.
+func (c *Codec) SetRawToString(rawToString bool) *Codec {
+	c.mutableHandle().RawToString = rawToString
+	return c
+}
+
+// SetMapType sets the type of the maps decoded into an interface,
+// default map[interface{}]interface{}.
+func (c *Codec) SetMapType(mapType reflect.Type) *Codec {
+	c.mutableHandle().MapType = mapType
+	return c
+}
+
+// SetStructToArray writes the structs as the arrays of the field values
+// instead of the maps.
+func (c *Codec) SetStructToArray(structToArray bool) *Codec {
+	c.mutableHandle().StructToArray = structToArray
+	return c
+}
+
+// RegisterExt registers the extension of the type with the tag, the type is
+// converted to and from the bytes of the extension type, see msgpack.BytesExt.
+// NOTE: it fails after the first use of the Codec.
+func (c *Codec) RegisterExt(rt reflect.Type, tag uint64, ext msgpack.BytesExt) error {
+	return c.mutableHandle().SetBytesExt(rt, tag, ext)
+}
 
 // ContentType always Returns "application/x-msgpack; charset=utf-8".
 func (*Codec) ContentType(_ any) string {
@@ -27,9 +131,85 @@ func (c *Codec) Marshal(v any) ([]byte, error) {
 func (c *Codec) Unmarshal(data []byte, v any) error {
 	return c.NewDecoder(bytes.NewReader(data)).Decode(v)
 }
-func (*Codec) NewDecoder(r io.Reader) codec.Decoder {
-	return msgpack.NewDecoder(r, new(msgpack.MsgpackHandle))
+func (c *Codec) NewDecoder(r io.Reader) codec.Decoder {
+	return DecoderWrapper{Decoder: msgpack.NewDecoder(r, c.handle()), codec: c}
+}
+
+// DecoderWrapper is a wrapper around a *msgpack.Decoder that adds
+// support for protos to the Decode method, and reports
+// the decode failures as *codec.FieldError.
+type DecoderWrapper struct {
+	*msgpack.Decoder
+	codec *Codec
+}
+
+// Decode wraps the embedded decoder's Decode method.
+// NOTE: the msgpack errors tell neither the field nor the value,
+// except the proto messages.
+func (d DecoderWrapper) Decode(v any) error {
+	if m, ok := v.(proto.Message); ok {
+		var raw any
+		if err := d.Decoder.Decode(&raw); err != nil {
+			return wrapError(err)
+		}
+		proto.Reset(m)
+		return d.codec.decodeMessage(raw, m.ProtoReflect(), "")
+	}
+	return wrapError(d.Decoder.Decode(v))
 }
-func (*Codec) NewEncoder(w io.Writer) codec.Encoder {
-	return msgpack.NewEncoder(w, new(msgpack.MsgpackHandle))
+
+// wrapError converts the decode failures into *codec.FieldError,
+// io.EOF is returned as is.
+func wrapError(err error) error {
+	if err == nil || errors.Is(err, io.EOF) {
+		return err
+	}
+	return &codec.FieldError{Err: err}
+}
+func (c *Codec) NewEncoder(w io.Writer) codec.Encoder {
+	return &EncoderWrapper{Encoder: msgpack.NewEncoder(w, c.handle()), codec: c, w: w}
+}
+
+// EncoderWrapper is a wrapper around a *msgpack.Encoder that adds
+// support for protos to the Encode method.
+type EncoderWrapper struct {
+	*msgpack.Encoder
+	codec *Codec
+	w     io.Writer
+	proto *msgpack.Encoder // the encoder of the proto messages, created at the first use.
 }
+
+// Encode wraps the embedded encoder's Encode method to support protos,
+// which are written by an encoder of the new spec, see Codec.
+func (e *EncoderWrapper) Encode(v any) error {
+	if m, ok := v.(proto.Message); ok {
+		value, err := e.codec.messageValue(m.ProtoReflect())
+		if err != nil {
+			return err
+		}
+		if e.proto == nil {
+			e.proto = msgpack.NewEncoder(e.w, protoHandle)
+		}
+		return e.proto.Encode(value)
+	}
+	return e.Encoder.Encode(v)
+}
+
+// NewStreamEncoder returns a StreamEncoder which writes the records as
+// consecutive msgpack values into "w", it implements codec.StreamMarshaler.
+func (c *Codec) NewStreamEncoder(w io.Writer) codec.StreamEncoder {
+	return streamEncoder{c.NewEncoder(w)}
+}
+
+// NewStreamDecoder returns a Decoder which reads the consecutive msgpack values from "r",
+// it implements codec.StreamMarshaler.
+func (c *Codec) NewStreamDecoder(r io.Reader) codec.Decoder {
+	return c.NewDecoder(r)
+}
+
+// streamEncoder is a codec.StreamEncoder without the end of stream.
+type streamEncoder struct {
+	codec.Encoder
+}
+
+func (streamEncoder) Close() error { return nil }
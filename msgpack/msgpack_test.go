@@ -2,9 +2,15 @@ package msgpack
 
 import (
 	"bytes"
+	"errors"
+	"io"
+	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/things-go/encoding/codec"
+	"github.com/things-go/encoding/testdata/examplepb"
 )
 
 func TestCodec_ContentType(t *testing.T) {
@@ -50,3 +56,135 @@ func TestCodec_Encoder_Decoder(t *testing.T) {
 
 	require.Equal(t, want, got)
 }
+
+func TestCodec_DecodeFieldError(t *testing.T) {
+	type value struct {
+		N int `msgpack:"n"`
+	}
+	m := Codec{}
+	var fe *codec.FieldError
+	require.ErrorAs(t, m.Unmarshal([]byte{0xc1}, &value{}), &fe)
+}
+
+func TestCodec_Stream(t *testing.T) {
+	m := Codec{}
+
+	buf := &bytes.Buffer{}
+	enc := m.NewStreamEncoder(buf)
+	for _, foo := range []string{"a", "b"} {
+		require.NoError(t, enc.Encode(&testMode{Foo: foo}))
+	}
+	require.NoError(t, enc.Close())
+
+	var got []string
+	dec := m.NewStreamDecoder(buf)
+	for {
+		v := &testMode{}
+		err := dec.Decode(v)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, v.Foo)
+	}
+	require.Equal(t, []string{"a", "b"}, got)
+}
+
+type point struct {
+	X, Y int8
+}
+
+type pointExt struct{}
+
+func (pointExt) WriteExt(v any) []byte {
+	p := v.(*point)
+	return []byte{byte(p.X), byte(p.Y)}
+}
+
+func (pointExt) ReadExt(dst any, src []byte) {
+	p := dst.(*point)
+	p.X, p.Y = int8(src[0]), int8(src[1])
+}
+
+func TestCodec_Handle(t *testing.T) {
+	t.Run("canonical", func(t *testing.T) {
+		m := New().SetCanonical(true)
+		b, err := m.Marshal(map[string]int{"c": 3, "a": 1, "b": 2})
+		require.NoError(t, err)
+		a, bb, c := bytes.IndexByte(b, 'a'), bytes.IndexByte(b, 'b'), bytes.IndexByte(b, 'c')
+		require.True(t, a < bb && bb < c, "the keys should be sorted: %x", b)
+	})
+	t.Run("write ext", func(t *testing.T) {
+		b, err := (&Codec{}).Marshal([]byte{1})
+		require.NoError(t, err)
+		require.Equal(t, byte(0xa1), b[0], "the old spec writes the raw type")
+
+		b, err = New().SetWriteExt(true).Marshal([]byte{1})
+		require.NoError(t, err)
+		require.Equal(t, byte(0xc4), b[0], "the new spec writes the bin type")
+	})
+	t.Run("raw to string", func(t *testing.T) {
+		b, err := (&Codec{}).Marshal("foo")
+		require.NoError(t, err)
+
+		var got any
+		require.NoError(t, (&Codec{}).Unmarshal(b, &got))
+		require.Equal(t, []byte("foo"), got)
+		got = nil
+		require.NoError(t, New().SetRawToString(true).Unmarshal(b, &got))
+		require.Equal(t, "foo", got)
+	})
+	t.Run("map type", func(t *testing.T) {
+		m := New().SetMapType(reflect.TypeOf(map[string]any(nil))).SetRawToString(true)
+		b, err := m.Marshal(map[string]any{"foo": "bar"})
+		require.NoError(t, err)
+
+		var got any
+		require.NoError(t, m.Unmarshal(b, &got))
+		require.Equal(t, map[string]any{"foo": "bar"}, got)
+	})
+	t.Run("struct to array", func(t *testing.T) {
+		m := New().SetStructToArray(true)
+		want := &testMode{Foo: "FOO"}
+		b, err := m.Marshal(want)
+		require.NoError(t, err)
+		require.Equal(t, byte(0x91), b[0], "the struct should be a fixarray")
+
+		got := &testMode{}
+		require.NoError(t, m.Unmarshal(b, got))
+		require.Equal(t, want, got)
+	})
+	t.Run("zero codec", func(t *testing.T) {
+		m := &Codec{}
+		require.NotPanics(t, func() { m.SetCanonical(true) })
+		require.NotNil(t, m.Handle)
+		require.True(t, m.Handle.Canonical)
+		require.False(t, defaultHandle.Canonical, "the shared handle is never modified")
+
+		m = &Codec{}
+		require.NoError(t, m.RegisterExt(reflect.TypeOf(point{}), 1, pointExt{}))
+		require.NotNil(t, m.Handle)
+		require.Nil(t, (&Codec{}).Handle)
+	})
+	t.Run("proto", func(t *testing.T) {
+		m := New().SetStructToArray(true)
+		b, err := m.Marshal(&examplepb.SimpleMessage{Id: "1"})
+		require.NoError(t, err)
+		require.Equal(t, byte(0x81), b[0], "the proto message is always a map")
+	})
+	t.Run("extension", func(t *testing.T) {
+		m := New().SetWriteExt(true)
+		require.NoError(t, m.RegisterExt(reflect.TypeOf(point{}), 1, pointExt{}))
+
+		want := &point{X: 1, Y: -1}
+		b, err := m.Marshal(want)
+		require.NoError(t, err)
+		require.Equal(t, []byte{0xd5, 0x01, 0x01, 0xff}, b)
+
+		got := &point{}
+		require.NoError(t, m.Unmarshal(b, got))
+		require.Equal(t, want, got)
+
+		require.Error(t, m.RegisterExt(reflect.TypeOf(testMode{}), 2, pointExt{}), "the handle is in use")
+	})
+}
@@ -0,0 +1,574 @@
+package msgpack
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	msgpack "github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/things-go/encoding/codec"
+	"github.com/things-go/encoding/internal/wellknown"
+)
+
+// mapSlice is the alternate keys and values which are encoded as a msgpack map in order.
+type mapSlice []any
+
+// MapBySlice implements msgpack.MapBySlice.
+func (mapSlice) MapBySlice() {}
+
+// protoHandle is the handle of the proto messages, which writes the
+// str and bin types, and the timestamp extension.
+var protoHandle = &msgpack.MsgpackHandle{WriteExt: true}
+
+// messageValue returns the msgpack value of the message,
+// the populated fields are the map entries in the field order.
+func (c *Codec) messageValue(m protoreflect.Message) (any, error) {
+	if v, ok, err := c.wellKnownValue(m); ok || err != nil {
+		return v, err
+	}
+	fields := m.Descriptor().Fields()
+	out := make(mapSlice, 0, 2*fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		v, err := c.fieldValue(fd, m.Get(fd))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, wellknown.FieldName(fd, c.UseProtoNames), v)
+	}
+	return out, nil
+}
+
+func (c *Codec) fieldValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) (any, error) {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		out := make([]any, list.Len())
+		for i := range out {
+			var err error
+			if out[i], err = c.singularValue(fd, list.Get(i)); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case fd.IsMap():
+		mp := v.Map()
+		keys := make([]protoreflect.MapKey, 0, mp.Len())
+		mp.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+			keys = append(keys, k)
+			return true
+		})
+		sort.Slice(keys, func(i, j int) bool { return lessMapKey(keys[i], keys[j]) })
+		out := make(mapSlice, 0, 2*len(keys))
+		for _, k := range keys {
+			value, err := c.singularValue(fd.MapValue(), mp.Get(k))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, k.Interface(), value)
+		}
+		return out, nil
+	}
+	return c.singularValue(fd, v)
+}
+
+func lessMapKey(a, b protoreflect.MapKey) bool {
+	switch a.Interface().(type) {
+	case bool:
+		return !a.Bool() && b.Bool()
+	case int32, int64:
+		return a.Int() < b.Int()
+	case uint32, uint64:
+		return a.Uint() < b.Uint()
+	}
+	return a.String() < b.String()
+}
+
+// singularValue returns the msgpack value of a singular value of the field,
+// an enum is the name, or the number if Codec.UseEnumNumbers or the number has no name.
+func (c *Codec) singularValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) (any, error) {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return c.messageValue(v.Message())
+	case protoreflect.EnumKind:
+		if fd.Enum().FullName() == "google.protobuf.NullValue" {
+			return nil, nil
+		}
+		if !c.UseEnumNumbers {
+			if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+				return string(ev.Name()), nil
+			}
+		}
+		return int64(v.Enum()), nil
+	}
+	return v.Interface(), nil
+}
+
+// wellKnownValue returns the msgpack value of the well-known types.
+func (c *Codec) wellKnownValue(m protoreflect.Message) (any, bool, error) {
+	md := m.Descriptor()
+	fields := md.Fields()
+	switch name := md.FullName(); {
+	case name == "google.protobuf.Timestamp":
+		return wellknown.Timestamp(m), true, nil
+	case name == "google.protobuf.Duration":
+		return wellknown.FormatDuration(m), true, nil
+	case name == "google.protobuf.FieldMask":
+		return wellknown.FormatFieldMask(m), true, nil
+	case wellknown.IsWrapper(name):
+		fd := fields.ByNumber(1)
+		v, err := c.singularValue(fd, m.Get(fd))
+		return v, true, err
+	case name == "google.protobuf.Struct", name == "google.protobuf.ListValue":
+		v, err := c.fieldValue(fields.ByNumber(1), m.Get(fields.ByNumber(1)))
+		return v, true, err
+	case name == "google.protobuf.Value":
+		fd := m.WhichOneof(md.Oneofs().ByName("kind"))
+		if fd == nil {
+			return nil, true, nil
+		}
+		v, err := c.singularValue(fd, m.Get(fd))
+		return v, true, err
+	case name == "google.protobuf.Any":
+		v, err := c.anyValue(m)
+		return v, true, err
+	}
+	return nil, false, nil
+}
+
+// anyValue returns the map of the "@type" key and the fields of the resolved message,
+// or the "@type" and the "value" keys if the resolved message is a well-known type.
+func (c *Codec) anyValue(m protoreflect.Message) (any, error) {
+	typeURL, inner, err := wellknown.UnpackAny(c.Resolver, m)
+	if err != nil {
+		return nil, err
+	}
+	if inner == nil {
+		return mapSlice{}, nil
+	}
+	v, err := c.messageValue(inner)
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := v.(mapSlice); ok && !isWellKnownType(inner.Descriptor().FullName()) {
+		return append(mapSlice{"@type", typeURL}, s...), nil
+	}
+	return mapSlice{"@type", typeURL, "value", v}, nil
+}
+
+func isWellKnownType(name protoreflect.FullName) bool {
+	return strings.HasPrefix(string(name), "google.protobuf.") && name != "google.protobuf.Empty"
+}
+
+// decodeMessage sets the fields of the message from the decoded msgpack value,
+// path is the dotted field path of the message for the errors.
+func (c *Codec) decodeMessage(raw any, m protoreflect.Message, path string) error {
+	md := m.Descriptor()
+	if ok, err := c.decodeWellKnown(raw, m, path); ok {
+		return err
+	}
+	entries, ok := toMap(raw)
+	if !ok {
+		return newTypeError(path, raw, string(md.FullName()))
+	}
+	for _, key := range sortedKeys(entries) {
+		name, ok := key.(string)
+		if !ok {
+			return &codec.FieldError{Field: path, Value: fmt.Sprint(key), Type: string(md.FullName()), Err: fmt.Errorf("msgpack: invalid field name of %T", key)}
+		}
+		fd := md.Fields().ByJSONName(name)
+		if fd == nil {
+			fd = md.Fields().ByTextName(name)
+		}
+		fieldPath := wellknown.JoinPath(path, name)
+		if fd == nil {
+			if c.DiscardUnknown {
+				continue
+			}
+			return &codec.FieldError{Field: fieldPath, Type: string(md.FullName()), Err: fmt.Errorf("msgpack: unknown field %q", name)}
+		}
+		value := entries[key]
+		if value == nil && !(fd.Message() != nil && fd.Message().FullName() == "google.protobuf.Value") {
+			continue
+		}
+		if err := c.decodeField(value, m, fd, fieldPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Codec) decodeField(raw any, m protoreflect.Message, fd protoreflect.FieldDescriptor, path string) error {
+	switch {
+	case fd.IsList():
+		items, ok := toSlice(raw)
+		if !ok {
+			return newTypeError(path, raw, "list")
+		}
+		list := m.Mutable(fd).List()
+		for i, item := range items {
+			v, err := c.decodeSingular(item, fd, list.NewElement(), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return err
+			}
+			list.Append(v)
+		}
+	case fd.IsMap():
+		entries, ok := toMap(raw)
+		if !ok {
+			return newTypeError(path, raw, "map")
+		}
+		mp := m.Mutable(fd).Map()
+		for _, key := range sortedKeys(entries) {
+			keyPath := wellknown.JoinPath(path, fmt.Sprint(key))
+			k, err := c.decodeScalar(key, fd.MapKey(), keyPath)
+			if err != nil {
+				return err
+			}
+			v, err := c.decodeSingular(entries[key], fd.MapValue(), mp.NewValue(), keyPath)
+			if err != nil {
+				return err
+			}
+			mp.Set(k.MapKey(), v)
+		}
+	default:
+		var v protoreflect.Value
+		if fd.Message() != nil {
+			v = m.Mutable(fd)
+		}
+		v, err := c.decodeSingular(raw, fd, v, path)
+		if err != nil {
+			return err
+		}
+		m.Set(fd, v)
+	}
+	return nil
+}
+
+// decodeSingular returns the singular value of the field, v is the new message of the message fields.
+func (c *Codec) decodeSingular(raw any, fd protoreflect.FieldDescriptor, v protoreflect.Value, path string) (protoreflect.Value, error) {
+	if fd.Message() != nil {
+		return v, c.decodeMessage(raw, v.Message(), path)
+	}
+	return c.decodeScalar(raw, fd, path)
+}
+
+// decodeScalar converts the decoded msgpack value into the scalar of the field,
+// an enum is either the name or the number.
+func (c *Codec) decodeScalar(raw any, fd protoreflect.FieldDescriptor, path string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if b, ok := raw.(bool); ok {
+			return protoreflect.ValueOfBool(b), nil
+		}
+	case protoreflect.EnumKind:
+		if s, ok := toString(raw); ok {
+			ev := fd.Enum().Values().ByName(protoreflect.Name(s))
+			if ev == nil {
+				return protoreflect.Value{}, &codec.FieldError{Field: path, Value: s, Type: string(fd.Enum().FullName()), Err: errors.New("msgpack: unknown enum value")}
+			}
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		if n, ok, err := toInt(raw, 32); ok {
+			return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), newRangeError(path, raw, fd, err)
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if n, ok, err := toInt(raw, 32); ok {
+			return protoreflect.ValueOfInt32(int32(n)), newRangeError(path, raw, fd, err)
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if n, ok, err := toInt(raw, 64); ok {
+			return protoreflect.ValueOfInt64(n), newRangeError(path, raw, fd, err)
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if n, ok, err := toUint(raw, 32); ok {
+			return protoreflect.ValueOfUint32(uint32(n)), newRangeError(path, raw, fd, err)
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if n, ok, err := toUint(raw, 64); ok {
+			return protoreflect.ValueOfUint64(n), newRangeError(path, raw, fd, err)
+		}
+	case protoreflect.FloatKind:
+		if f, ok := toFloat(raw); ok {
+			return protoreflect.ValueOfFloat32(float32(f)), nil
+		}
+	case protoreflect.DoubleKind:
+		if f, ok := toFloat(raw); ok {
+			return protoreflect.ValueOfFloat64(f), nil
+		}
+	case protoreflect.StringKind:
+		if s, ok := toString(raw); ok {
+			return protoreflect.ValueOfString(s), nil
+		}
+	case protoreflect.BytesKind:
+		switch b := raw.(type) {
+		case []byte:
+			return protoreflect.ValueOfBytes(b), nil
+		case string:
+			return protoreflect.ValueOfBytes([]byte(b)), nil
+		}
+	}
+	return protoreflect.Value{}, newTypeError(path, raw, fd.Kind().String())
+}
+
+// decodeWellKnown sets the well-known type from the decoded msgpack value,
+// it reports false if the message is not a well-known type which is not a map.
+func (c *Codec) decodeWellKnown(raw any, m protoreflect.Message, path string) (bool, error) {
+	md := m.Descriptor()
+	fields := md.Fields()
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		var t time.Time
+		switch v := raw.(type) {
+		case time.Time:
+			t = v
+		default:
+			s, ok := toString(raw)
+			if !ok {
+				return true, newTypeError(path, raw, string(md.FullName()))
+			}
+			var err error
+			if t, err = time.Parse(time.RFC3339Nano, s); err != nil {
+				return true, &codec.FieldError{Field: path, Value: s, Type: string(md.FullName()), Err: err}
+			}
+		}
+		wellknown.SetTimestamp(m, t)
+	case "google.protobuf.Duration":
+		s, ok := toString(raw)
+		if !ok {
+			return true, newTypeError(path, raw, string(md.FullName()))
+		}
+		if err := wellknown.SetDuration(m, s); err != nil {
+			return true, &codec.FieldError{Field: path, Value: s, Type: string(md.FullName()), Err: err}
+		}
+	case "google.protobuf.FieldMask":
+		s, ok := toString(raw)
+		if !ok {
+			return true, newTypeError(path, raw, string(md.FullName()))
+		}
+		wellknown.SetFieldMask(m, s)
+	case "google.protobuf.Struct", "google.protobuf.ListValue":
+		return true, c.decodeField(raw, m, fields.ByNumber(1), path)
+	case "google.protobuf.Value":
+		return true, c.decodeValue(raw, m, path)
+	case "google.protobuf.Any":
+		return true, c.decodeAny(raw, m, path)
+	default:
+		if !wellknown.IsWrapper(md.FullName()) {
+			return false, nil
+		}
+		fd := fields.ByNumber(1)
+		v, err := c.decodeScalar(raw, fd, path)
+		if err != nil {
+			return true, err
+		}
+		m.Set(fd, v)
+	}
+	return true, nil
+}
+
+// decodeValue sets the google.protobuf.Value from the decoded msgpack value.
+func (c *Codec) decodeValue(raw any, m protoreflect.Message, path string) error {
+	fields := m.Descriptor().Fields()
+	switch v := raw.(type) {
+	case nil:
+		m.Set(fields.ByName("null_value"), protoreflect.ValueOfEnum(0))
+	case bool:
+		m.Set(fields.ByName("bool_value"), protoreflect.ValueOfBool(v))
+	case string, []byte:
+		s, _ := toString(v)
+		m.Set(fields.ByName("string_value"), protoreflect.ValueOfString(s))
+	default:
+		if f, ok := toFloat(raw); ok {
+			m.Set(fields.ByName("number_value"), protoreflect.ValueOfFloat64(f))
+			return nil
+		}
+		if _, ok := toMap(raw); ok {
+			return c.decodeMessage(raw, m.Mutable(fields.ByName("struct_value")).Message(), path)
+		}
+		if _, ok := toSlice(raw); ok {
+			return c.decodeMessage(raw, m.Mutable(fields.ByName("list_value")).Message(), path)
+		}
+		return newTypeError(path, raw, "google.protobuf.Value")
+	}
+	return nil
+}
+
+// decodeAny sets the Any from the map of the "@type" key and the fields of the message,
+// or the "value" key if the message is a well-known type.
+func (c *Codec) decodeAny(raw any, m protoreflect.Message, path string) error {
+	entries, ok := toMap(raw)
+	if !ok {
+		return newTypeError(path, raw, "google.protobuf.Any")
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	typeURL, _ := toString(entries["@type"])
+	if typeURL == "" {
+		return &codec.FieldError{Field: wellknown.JoinPath(path, "@type"), Type: "google.protobuf.Any", Err: errors.New("msgpack: missing the type URL")}
+	}
+	mt, err := wellknown.Resolver(c.Resolver).FindMessageByURL(typeURL)
+	if err != nil {
+		return &codec.FieldError{Field: path, Value: typeURL, Type: "google.protobuf.Any", Err: err}
+	}
+	inner := mt.New()
+	if isWellKnownType(inner.Descriptor().FullName()) {
+		err = c.decodeMessage(entries["value"], inner, wellknown.JoinPath(path, "value"))
+	} else {
+		fields := make(map[any]any, len(entries))
+		for k, v := range entries {
+			if k != "@type" {
+				fields[k] = v
+			}
+		}
+		err = c.decodeMessage(fields, inner, path)
+	}
+	if err != nil {
+		return err
+	}
+	return wellknown.PackAny(m, typeURL, inner)
+}
+
+// toMap returns the entries of the decoded msgpack map of any type.
+func toMap(raw any) (map[any]any, bool) {
+	if m, ok := raw.(map[any]any); ok {
+		return m, true
+	}
+	rv := reflect.ValueOf(raw)
+	if rv.Kind() != reflect.Map {
+		return nil, false
+	}
+	m := make(map[any]any, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		m[iter.Key().Interface()] = iter.Value().Interface()
+	}
+	return m, true
+}
+
+// sortedKeys returns the keys of the map in order, so the errors are deterministic.
+func sortedKeys(m map[any]any) []any {
+	keys := make([]any, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+	return keys
+}
+
+// toSlice returns the items of the decoded msgpack array of any type.
+func toSlice(raw any) ([]any, bool) {
+	if s, ok := raw.([]any); ok {
+		return s, true
+	}
+	rv := reflect.ValueOf(raw)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	s := make([]any, rv.Len())
+	for i := range s {
+		s[i] = rv.Index(i).Interface()
+	}
+	return s, true
+}
+
+// toString returns the text of the str or legacy raw value.
+func toString(raw any) (string, bool) {
+	switch v := raw.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	}
+	return "", false
+}
+
+// toInt returns the integer of the bit size, ok reports whether raw is a number,
+// err reports the out of range or the fraction.
+func toInt(raw any, bitSize int) (n int64, ok bool, err error) {
+	rv := reflect.ValueOf(raw)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return 0, true, strconv.ErrRange
+		}
+		n = int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return 0, true, strconv.ErrRange
+		}
+		n = int64(f)
+	default:
+		return 0, false, nil
+	}
+	if bitSize < 64 && (n < -1<<(bitSize-1) || n >= 1<<(bitSize-1)) {
+		return 0, true, strconv.ErrRange
+	}
+	return n, true, nil
+}
+
+// toUint returns the unsigned integer of the bit size, ok reports whether raw is a number,
+// err reports the out of range or the fraction.
+func toUint(raw any, bitSize int) (n uint64, ok bool, err error) {
+	rv := reflect.ValueOf(raw)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Int() < 0 {
+			return 0, true, strconv.ErrRange
+		}
+		n = uint64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n = rv.Uint()
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+			return 0, true, strconv.ErrRange
+		}
+		n = uint64(f)
+	default:
+		return 0, false, nil
+	}
+	if bitSize < 64 && n >= 1<<bitSize {
+		return 0, true, strconv.ErrRange
+	}
+	return n, true, nil
+}
+
+// toFloat returns the float of any number.
+func toFloat(raw any) (float64, bool) {
+	rv := reflect.ValueOf(raw)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func newTypeError(path string, raw any, typ string) error {
+	return &codec.FieldError{Field: path, Value: fmt.Sprint(raw), Type: typ, Err: fmt.Errorf("msgpack: cannot decode %T", raw)}
+}
+
+func newRangeError(path string, raw any, fd protoreflect.FieldDescriptor, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codec.FieldError{Field: path, Value: fmt.Sprint(raw), Type: fd.Kind().String(), Err: err}
+}
@@ -0,0 +1,172 @@
+package msgpack
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	msgpack "github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/things-go/encoding/codec"
+	"github.com/things-go/encoding/testdata/examplepb"
+)
+
+var complexMessage = &examplepb.Complex{
+	Id:        2233,
+	NoOne:     "2233",
+	Simple:    &examplepb.Simple{Component: "5566"},
+	Simples:   []string{"3344", "5566"},
+	B:         true,
+	Sex:       examplepb.Sex_woman,
+	Age:       18,
+	A:         19,
+	Count:     3,
+	Price:     11.5,
+	D:         22.25,
+	Byte:      []byte("123"),
+	Timestamp: timestamppb.New(time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)),
+	Duration:  durationpb.New(1500 * time.Millisecond),
+	Field:     &fieldmaskpb.FieldMask{Paths: []string{"a", "b.c"}},
+	Double:    wrapperspb.Double(12.5),
+	Float:     wrapperspb.Float(12.5),
+	Int64:     wrapperspb.Int64(-1),
+	Int32:     wrapperspb.Int32(-2),
+	Uint64:    wrapperspb.UInt64(1 << 63),
+	Uint32:    wrapperspb.UInt32(4),
+	Bool:      wrapperspb.Bool(false),
+	String_:   wrapperspb.String("go"),
+	Bytes:     wrapperspb.Bytes([]byte("bytes")),
+	Map:       map[string]string{"b": "2", "a": "1"},
+}
+
+// decodeRaw decodes the msgpack value with the new spec.
+func decodeRaw(t *testing.T, b []byte) map[string]any {
+	var raw map[string]any
+	require.NoError(t, msgpack.NewDecoderBytes(b, &msgpack.MsgpackHandle{WriteExt: true}).Decode(&raw))
+	return raw
+}
+
+func TestCodec_Proto(t *testing.T) {
+	m := &Codec{}
+
+	b, err := m.Marshal(complexMessage)
+	require.NoError(t, err)
+
+	raw := decodeRaw(t, b)
+	require.Equal(t, "woman", raw["sex"])
+	require.Equal(t, "2233", raw["numberOne"])
+	require.Equal(t, []byte("123"), raw["byte"])
+	require.Equal(t, complexMessage.Timestamp.AsTime(), raw["timestamp"])
+	require.Equal(t, "1.5s", raw["duration"])
+	require.Equal(t, "a,b.c", raw["field"])
+	require.Equal(t, "go", raw["string"])
+
+	got := &examplepb.Complex{}
+	require.NoError(t, m.Unmarshal(b, got))
+	require.True(t, proto.Equal(complexMessage, got), "got %v; want %v", got, complexMessage)
+
+	b2, err := m.Marshal(complexMessage)
+	require.NoError(t, err)
+	require.Equal(t, b, b2, "the encoding should be deterministic")
+}
+
+func TestCodec_ProtoOptions(t *testing.T) {
+	m := &Codec{UseProtoNames: true, UseEnumNumbers: true}
+	want := &examplepb.Complex{NoOne: "1", Sex: examplepb.Sex_woman}
+
+	b, err := m.Marshal(want)
+	require.NoError(t, err)
+	raw := decodeRaw(t, b)
+	require.Equal(t, "1", raw["no_one"])
+	require.EqualValues(t, 1, raw["sex"])
+
+	got := &examplepb.Complex{}
+	require.NoError(t, m.Unmarshal(b, got))
+	require.True(t, proto.Equal(want, got), "got %v; want %v", got, want)
+}
+
+func TestCodec_ProtoWellKnownTypes(t *testing.T) {
+	m := &Codec{}
+
+	anyValue, err := anypb.New(&examplepb.SimpleMessage{Id: "foo"})
+	require.NoError(t, err)
+	anyWellKnown, err := anypb.New(durationpb.New(time.Second))
+	require.NoError(t, err)
+	structValue, err := structpb.NewStruct(map[string]any{
+		"a": 1.5,
+		"b": "str",
+		"c": []any{true, nil},
+		"d": map[string]any{"e": "f"},
+	})
+	require.NoError(t, err)
+
+	for _, want := range []proto.Message{anyValue, anyWellKnown, structValue, structpb.NewNullValue()} {
+		b, err := m.Marshal(want)
+		require.NoError(t, err)
+
+		got := want.ProtoReflect().New().Interface()
+		require.NoError(t, m.Unmarshal(b, got))
+		require.True(t, proto.Equal(want, got), "got %v; want %v", got, want)
+	}
+}
+
+func TestCodec_ProtoEncoderDecoder(t *testing.T) {
+	m := &Codec{}
+	values := []*examplepb.SimpleMessage{{Id: "1"}, {Id: "2"}}
+
+	buf := &bytes.Buffer{}
+	enc := m.NewStreamEncoder(buf)
+	for _, v := range values {
+		require.NoError(t, enc.Encode(v))
+	}
+	dec := m.NewStreamDecoder(buf)
+	for _, want := range values {
+		got := &examplepb.SimpleMessage{}
+		require.NoError(t, dec.Decode(got))
+		require.True(t, proto.Equal(want, got), "got %v; want %v", got, want)
+	}
+}
+
+func TestCodec_ProtoDecodeFieldError(t *testing.T) {
+	encode := func(v any) []byte {
+		var b []byte
+		require.NoError(t, msgpack.NewEncoderBytes(&b, &msgpack.MsgpackHandle{WriteExt: true}).Encode(v))
+		return b
+	}
+
+	tests := []struct {
+		name  string
+		value any
+		field string
+	}{
+		{name: "unknown", value: map[string]any{"foo": 1}, field: "foo"},
+		{name: "type", value: map[string]any{"id": "a"}, field: "id"},
+		{name: "range", value: map[string]any{"age": int64(1) << 40}, field: "age"},
+		{name: "enum", value: map[string]any{"sex": "other"}, field: "sex"},
+		{name: "nested", value: map[string]any{"very_simple": map[string]any{"component": 1}}, field: "very_simple.component"},
+		{name: "duration", value: map[string]any{"duration": "1"}, field: "duration"},
+	}
+	m := &Codec{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fe *codec.FieldError
+			require.ErrorAs(t, m.Unmarshal(encode(tt.value), &examplepb.Complex{}), &fe)
+			require.Equal(t, tt.field, fe.Field)
+		})
+	}
+
+	t.Run("discard unknown", func(t *testing.T) {
+		m := &Codec{DiscardUnknown: true}
+		got := &examplepb.Complex{}
+		require.NoError(t, m.Unmarshal(encode(map[string]any{"foo": 1, "id": 2}), got))
+		require.EqualValues(t, 2, got.Id)
+	})
+}
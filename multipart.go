@@ -0,0 +1,231 @@
+package encoding
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"reflect"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/things-go/encoding/form"
+)
+
+// TagFile selects the multipart file part name of a field,
+// if not set, the field name of the multipart codec's tag is used.
+const TagFile = "file"
+
+const httpBodyMessageFullname protoreflect.FullName = "google.api.HttpBody"
+
+var (
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeaderSliceType = reflect.TypeOf([]*multipart.FileHeader(nil))
+	bytesType           = reflect.TypeOf([]byte(nil))
+	readerType          = reflect.TypeOf((*io.Reader)(nil)).Elem()
+)
+
+// copyFile copies the multipart file part, the file exceeding MaxFileSize is detected while
+// it is read, and the file whose `Content-Type` is not allowed is rejected before it is read.
+func (l *Limits) copyFile(w io.Writer, p *multipart.Part) error {
+	if len(l.AllowedFileTypes) > 0 && !allowedFileType(l.AllowedFileTypes, p.Header.Get(contentTypeHeader)) {
+		return &MediaTypeError{
+			Err:       ErrUnsupportedMediaType,
+			MediaType: p.Header.Get(contentTypeHeader),
+			Supported: l.AllowedFileTypes,
+		}
+	}
+	if l.MaxFileSize <= 0 {
+		_, err := io.Copy(w, p)
+		return err
+	}
+	// read one more byte to detect whether exceeds the limit.
+	n, err := io.Copy(w, io.LimitReader(p, l.MaxFileSize+1))
+	if err != nil {
+		return err
+	}
+	if n > l.MaxFileSize {
+		return &LimitError{Limit: "MaxFileSize", Max: l.MaxFileSize}
+	}
+	return nil
+}
+
+// allowedFileType reports whether the file's `Content-Type` matches one of the
+// allowed media ranges, e.g. "image/png" or "image/*".
+func allowedFileType(allowed []string, contentType string) bool {
+	mediaType := baseMediaType(contentType)
+	if mediaType == "" {
+		return false
+	}
+	for _, v := range allowed {
+		rg := &mediaRange{mediaType: strings.ToLower(v)}
+		if rg.match(mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// bindFiles binds the multipart files into the fields of the struct pointer or proto message v.
+//
+// For a struct, the top level fields (the fields of embedded structs included) of type
+// *multipart.FileHeader, []*multipart.FileHeader, []byte and io.Reader are bound, the
+// part name is the `file` tag, otherwise the field name of the codec's tag.
+// The io.Reader is a *bytes.Reader of the file content, so there is nothing to close.
+// For a proto message, the top level bytes, repeated bytes, google.api.HttpBody and
+// repeated google.api.HttpBody fields are bound, the part name is the proto name or JSON name.
+func bindFiles(files map[string][]*multipart.FileHeader, v any, tagName string) error {
+	if len(files) == 0 {
+		return nil
+	}
+	if m, ok := v.(proto.Message); ok {
+		return bindProtoFiles(files, m.ProtoReflect())
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct || !rv.CanAddr() {
+		return nil
+	}
+	if m, ok := rv.Addr().Interface().(proto.Message); ok {
+		return bindProtoFiles(files, m.ProtoReflect())
+	}
+	return bindStructFiles(files, rv, tagName)
+}
+
+func bindStructFiles(files map[string][]*multipart.FileHeader, rv reflect.Value, tagName string) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		fv := rv.Field(i)
+		if sf.Anonymous {
+			if fv.Kind() == reflect.Ptr && sf.Type.Elem().Kind() == reflect.Struct && sf.IsExported() {
+				if fv.IsNil() {
+					fv.Set(reflect.New(sf.Type.Elem()))
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if err := bindStructFiles(files, fv, tagName); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get(TagFile), ",")
+		if name == "" {
+			name, _, _ = strings.Cut(sf.Tag.Get(tagName), ",")
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fhs := files[name]
+		if name == "-" || len(fhs) == 0 {
+			continue
+		}
+		switch sf.Type {
+		case fileHeaderType:
+			fv.Set(reflect.ValueOf(fhs[0]))
+		case fileHeaderSliceType:
+			fv.Set(reflect.ValueOf(fhs))
+		case bytesType:
+			b, err := readFile(fhs[0])
+			if err != nil {
+				return err
+			}
+			fv.SetBytes(b)
+		case readerType:
+			b, err := readFile(fhs[0])
+			if err != nil {
+				return err
+			}
+			fv.Set(reflect.ValueOf(bytes.NewReader(b)))
+		}
+	}
+	return nil
+}
+
+func bindProtoFiles(files map[string][]*multipart.FileHeader, m protoreflect.Message) error {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		fhs := files[string(fd.Name())]
+		if len(fhs) == 0 && fd.HasJSONName() {
+			fhs = files[fd.JSONName()]
+		}
+		if len(fhs) == 0 || fd.IsMap() {
+			continue
+		}
+		switch {
+		case fd.Kind() == protoreflect.BytesKind:
+			if !fd.IsList() {
+				fhs = fhs[:1]
+			}
+			for _, fh := range fhs {
+				b, err := readFile(fh)
+				if err != nil {
+					return err
+				}
+				if !fd.IsList() {
+					m.Set(fd, protoreflect.ValueOfBytes(b))
+					break
+				}
+				m.Mutable(fd).List().Append(protoreflect.ValueOfBytes(b))
+			}
+		case fd.Kind() == protoreflect.MessageKind && fd.Message().FullName() == httpBodyMessageFullname:
+			if !fd.IsList() {
+				if err := setHTTPBody(m.Mutable(fd).Message(), fhs[0]); err != nil {
+					return err
+				}
+				continue
+			}
+			list := m.Mutable(fd).List()
+			for _, fh := range fhs {
+				body := list.NewElement()
+				if err := setHTTPBody(body.Message(), fh); err != nil {
+					return err
+				}
+				list.Append(body)
+			}
+		}
+	}
+	return nil
+}
+
+// setHTTPBody sets the google.api.HttpBody message with the file.
+func setHTTPBody(m protoreflect.Message, fh *multipart.FileHeader) error {
+	b, err := readFile(fh)
+	if err != nil {
+		return err
+	}
+	fields := m.Descriptor().Fields()
+	m.Set(fields.ByName("content_type"), protoreflect.ValueOfString(fh.Header.Get(contentTypeHeader)))
+	m.Set(fields.ByName("data"), protoreflect.ValueOfBytes(b))
+	return nil
+}
+
+func readFile(fh *multipart.FileHeader) ([]byte, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, fmt.Errorf("encoding: open multipart file %q: %w", fh.Filename, err)
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// multipartTagName returns the struct tag name of the multipart codec.
+func multipartTagName(m any) string {
+	if c, ok := m.(*form.MultipartCodec); ok && c.Codec != nil {
+		return c.TagName
+	}
+	return "json"
+}
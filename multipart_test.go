@@ -0,0 +1,130 @@
+package encoding
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/things-go/encoding/testdata/examplepb"
+)
+
+type UploadMeta struct {
+	Raw []byte `json:"raw"`
+}
+
+type UploadMode struct {
+	*UploadMeta
+	Name   string                  `json:"name"`
+	Avatar *multipart.FileHeader   `json:"avatar"`
+	Photos []*multipart.FileHeader `file:"photo"`
+	Reader io.Reader               `json:"reader"`
+	Skip   []byte                  `json:"-"`
+}
+
+type multipartFile struct {
+	field       string
+	filename    string
+	contentType string
+	content     string
+}
+
+func newMultipartRequest(t *testing.T, values map[string]string, files ...multipartFile) *http.Request {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for k, v := range values {
+		require.NoError(t, mw.WriteField(k, v))
+	}
+	for _, f := range files {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="`+f.field+`"; filename="`+f.filename+`"`)
+		h.Set("Content-Type", f.contentType)
+		w, err := mw.CreatePart(h)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(f.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+	r := httptest.NewRequest(http.MethodPost, "http://example.com", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func Test_Encoding_Bind_MultipartFiles(t *testing.T) {
+	files := []multipartFile{
+		{"avatar", "avatar.png", "image/png", "avatar"},
+		{"photo", "1.jpg", "image/jpeg", "photo1"},
+		{"photo", "2.jpg", "image/jpeg", "photo2"},
+		{"raw", "raw.bin", "application/octet-stream", "raw"},
+		{"reader", "reader.txt", "text/plain", "reader"},
+		{"Skip", "skip.txt", "text/plain", "skip"},
+	}
+
+	t.Run("struct", func(t *testing.T) {
+		got := UploadMode{}
+		err := New().Bind(newMultipartRequest(t, map[string]string{"name": "foo"}, files...), &got)
+		require.NoError(t, err)
+		require.Equal(t, "foo", got.Name)
+		require.NotNil(t, got.Avatar)
+		require.Equal(t, "avatar.png", got.Avatar.Filename)
+		require.Len(t, got.Photos, 2)
+		require.Equal(t, "2.jpg", got.Photos[1].Filename)
+		require.Equal(t, []byte("raw"), got.Raw)
+		require.IsType(t, &bytes.Reader{}, got.Reader, "the file is read in memory")
+		b, err := io.ReadAll(got.Reader)
+		require.NoError(t, err)
+		require.Equal(t, "reader", string(b))
+		require.Nil(t, got.Skip)
+	})
+	t.Run("proto", func(t *testing.T) {
+		got := &examplepb.ABitOfEverything{}
+		err := New().Bind(newMultipartRequest(t, map[string]string{"uuid": "foo"},
+			multipartFile{"bytes_value", "a.bin", "application/octet-stream", "bytes"},
+		), got)
+		require.NoError(t, err)
+		require.Equal(t, "foo", got.GetUuid())
+		require.Equal(t, []byte("bytes"), got.GetBytesValue())
+
+		got = &examplepb.ABitOfEverything{}
+		err = New().Bind(newMultipartRequest(t, nil,
+			multipartFile{"bytesValue", "a.bin", "application/octet-stream", "json name"},
+		), got)
+		require.NoError(t, err)
+		require.Equal(t, []byte("json name"), got.GetBytesValue())
+	})
+	t.Run("max file size", func(t *testing.T) {
+		got := UploadMode{}
+		registry := New().SetLimits(Limits{MaxFileSize: 5})
+		err := registry.Bind(newMultipartRequest(t, nil, files...), &got)
+		require.ErrorIs(t, err, ErrBodyTooLarge)
+		var limitErr *LimitError
+		require.ErrorAs(t, err, &limitErr)
+		require.Equal(t, "MaxFileSize", limitErr.Limit)
+
+		// the rest of the file exceeding the limit is not read.
+		req := newMultipartRequest(t, nil, multipartFile{"raw", "raw.bin", "application/octet-stream", strings.Repeat("x", 8<<10)})
+		req.Body = io.NopCloser(io.MultiReader(io.LimitReader(req.Body, 6<<10), iotest.ErrReader(errors.New("read after the limit"))))
+		err = New().SetLimits(Limits{MaxFileSize: 16}).Bind(req, &got)
+		require.ErrorIs(t, err, ErrBodyTooLarge)
+	})
+	t.Run("allowed file types", func(t *testing.T) {
+		got := UploadMode{}
+		registry := New().SetLimits(Limits{AllowedFileTypes: []string{"image/*"}})
+		err := registry.Bind(newMultipartRequest(t, nil, files[:3]...), &got)
+		require.NoError(t, err)
+
+		err = registry.Bind(newMultipartRequest(t, nil, files...), &got)
+		require.ErrorIs(t, err, ErrUnsupportedMediaType)
+		var mtErr *MediaTypeError
+		require.ErrorAs(t, err, &mtErr)
+		require.Equal(t, "application/octet-stream", mtErr.MediaType, "the first offending file in order")
+	})
+}
@@ -0,0 +1,242 @@
+// Package ndjson implements the newline-delimited JSON (NDJSON, JSON Lines) codec,
+// which streams a sequence of JSON records separated by "\n".
+// see https://github.com/ndjson/ndjson-spec and https://jsonlines.org
+package ndjson
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/things-go/encoding/codec"
+	jsoncodec "github.com/things-go/encoding/json"
+	"github.com/things-go/encoding/jsonpb"
+)
+
+const delimiter = "\n"
+
+var (
+	defaultJSON  codec.Marshaler = &jsoncodec.Codec{}
+	defaultProto codec.Marshaler = &jsonpb.Codec{}
+)
+
+// Codec is a Marshaler which marshals/unmarshals a sequence of JSON records
+// separated by "\n", each record is a single line.
+//
+// A slice (except []byte) is encoded as a record per element, and decoded
+// from all the records. Other values are encoded and decoded as a single record.
+// The NewEncoder writes the records of each value as soon as it is encoded,
+// the NewDecoder decodes a record per Decode call (or all the remaining records
+// into a slice), so the large streams can be processed one record at a time, see codec.Iter.
+type Codec struct {
+	// Marshaler marshals/unmarshals each record, the records with line breaks are compacted.
+	// If nil, the proto messages use jsonpb.Codec, and others use json.Codec.
+	Marshaler codec.Marshaler
+	// MaxRecordSize is the maximum size of a record to decode, 0 means no limit.
+	MaxRecordSize int
+}
+
+// ErrRecordTooLarge is reported when a record exceeds the MaxRecordSize,
+// it wraps codec.ErrTooLarge. The Decoder reports it on every Decode after it,
+// since the rest of the record is not read.
+var ErrRecordTooLarge = codec.NewTooLargeError("ndjson: record too large")
+
+// ContentType always Returns "application/x-ndjson".
+func (*Codec) ContentType(_ any) string {
+	return "application/x-ndjson"
+}
+
+// Marshal marshals v into the records, each terminated by "\n".
+func (c *Codec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal unmarshals the records into v, v must be a pointer to slice
+// for multiple records.
+func (c *Codec) Unmarshal(data []byte, v any) error {
+	err := c.NewDecoder(bytes.NewReader(data)).Decode(v)
+	if errors.Is(err, io.EOF) && !isSlicePtr(v) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// NewEncoder returns an Encoder which writes the records into "w".
+func (c *Codec) NewEncoder(w io.Writer) codec.Encoder {
+	return codec.EncoderFunc(func(v any) error {
+		rv := reflect.ValueOf(v)
+		if !isSlice(rv) {
+			return c.writeRecord(w, v)
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := c.writeRecord(w, rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// NewDecoder returns a Decoder which reads the records from "r",
+// it returns io.EOF if there is no more record.
+// The empty lines are ignored.
+func (c *Codec) NewDecoder(r io.Reader) codec.Decoder {
+	return &Decoder{r: bufio.NewReader(r), codec: c}
+}
+
+// NewStreamEncoder returns a StreamEncoder which writes a record per Encode into "w",
+// it implements codec.StreamMarshaler.
+func (c *Codec) NewStreamEncoder(w io.Writer) codec.StreamEncoder {
+	return streamEncoder{c.NewEncoder(w)}
+}
+
+// NewStreamDecoder returns a Decoder which reads a record per Decode from "r",
+// it implements codec.StreamMarshaler.
+func (c *Codec) NewStreamDecoder(r io.Reader) codec.Decoder {
+	return c.NewDecoder(r)
+}
+
+// Delimiter returns the record delimiter "\n".
+func (c *Codec) Delimiter() []byte {
+	return []byte(delimiter)
+}
+
+// streamEncoder is a codec.StreamEncoder without the end of stream.
+type streamEncoder struct {
+	codec.Encoder
+}
+
+func (streamEncoder) Close() error { return nil }
+
+func (c *Codec) marshaler(v any) codec.Marshaler {
+	if c.Marshaler != nil {
+		return c.Marshaler
+	}
+	if _, ok := v.(proto.Message); ok {
+		return defaultProto
+	}
+	return defaultJSON
+}
+
+func (c *Codec) writeRecord(w io.Writer, v any) error {
+	b, err := c.marshaler(v).Marshal(v)
+	if err != nil {
+		return err
+	}
+	if bytes.ContainsAny(b, "\r\n") {
+		var buf bytes.Buffer
+		if err = json.Compact(&buf, b); err != nil {
+			return err
+		}
+		b = buf.Bytes()
+	}
+	b = bytes.TrimSpace(b)
+	_, err = w.Write(append(b, delimiter...))
+	return err
+}
+
+// Decoder decodes the records from a stream.
+type Decoder struct {
+	r     *bufio.Reader
+	codec *Codec
+	line  int
+	err   error // the read error after the last record.
+}
+
+// Decode decodes the next record into v, or all the remaining records into v
+// if v is a pointer to slice (except []byte). It returns io.EOF if there is no
+// more record. The decode failures are reported as *codec.FieldError with the
+// line number in the message, e.g. "line 3: ...", the Field is the record's field.
+func (d *Decoder) Decode(v any) error {
+	if !isSlicePtr(v) {
+		return d.decode(v)
+	}
+	rv := reflect.ValueOf(v).Elem()
+	elemType := rv.Type().Elem()
+	for {
+		elem := reflect.New(elemType)
+		target := elem.Interface()
+		if elemType.Kind() == reflect.Pointer {
+			elem.Elem().Set(reflect.New(elemType.Elem()))
+			target = elem.Elem().Interface()
+		}
+		if err := d.decode(target); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		rv.Set(reflect.Append(rv, elem.Elem()))
+	}
+}
+
+func (d *Decoder) decode(v any) error {
+	line, err := d.readRecord()
+	if err != nil {
+		return err
+	}
+	if err = d.codec.marshaler(v).Unmarshal(line, v); err != nil {
+		var fe *codec.FieldError
+		if errors.As(err, &fe) {
+			fe.Err = fmt.Errorf("line %d: %w", d.line, fe.Err)
+			return err
+		}
+		return &codec.FieldError{Err: fmt.Errorf("line %d: %w", d.line, err)}
+	}
+	return nil
+}
+
+// readRecord returns the next non-empty line, the line break excluded.
+// The read error of a last line without the line break is returned after the line.
+func (d *Decoder) readRecord() ([]byte, error) {
+	for {
+		if d.err != nil {
+			return nil, d.err
+		}
+		line, err := d.readLine()
+		if err != nil {
+			if len(line) == 0 {
+				return nil, err
+			}
+			d.err = err
+		}
+		d.line++
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			return line, nil
+		}
+	}
+}
+
+func (d *Decoder) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := d.r.ReadSlice('\n')
+		line = append(line, chunk...)
+		if limit := d.codec.MaxRecordSize; limit > 0 && len(bytes.TrimRight(line, "\r\n")) > limit {
+			d.err = ErrRecordTooLarge
+			return nil, d.err
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return line, err
+		}
+	}
+}
+
+func isSlice(rv reflect.Value) bool {
+	return rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8
+}
+
+func isSlicePtr(v any) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && !rv.IsNil() && isSlice(rv.Elem())
+}
@@ -0,0 +1,261 @@
+package ndjson
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/things-go/encoding/codec"
+	"github.com/things-go/encoding/jsonpb"
+	"github.com/things-go/encoding/testdata/examplepb"
+)
+
+type record struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestCodec_ContentType(t *testing.T) {
+	var m Codec
+
+	want := "application/x-ndjson"
+	if got := m.ContentType(struct{}{}); got != want {
+		t.Errorf("m.ContentType(_) failed, got = %q; want %q; ", got, want)
+	}
+}
+
+func TestCodec_Delimiter(t *testing.T) {
+	var m Codec
+
+	m.Delimiter()[0] = ','
+	if got := m.Delimiter(); string(got) != "\n" {
+		t.Errorf("m.Delimiter() got = %q; want %q", got, "\n")
+	}
+	got, err := m.Marshal(&record{1, "a"})
+	if err != nil {
+		t.Fatalf("m.Marshal() failed with %v; want success", err)
+	}
+	if want := `{"id":1,"name":"a"}` + "\n"; string(got) != want {
+		t.Errorf("m.Marshal() got = %q; want %q", got, want)
+	}
+}
+
+func TestCodec_Marshal(t *testing.T) {
+	var m Codec
+
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"single", &record{1, "a"}, `{"id":1,"name":"a"}` + "\n"},
+		{"slice", []record{{1, "a"}, {2, "b"}}, `{"id":1,"name":"a"}` + "\n" + `{"id":2,"name":"b"}` + "\n"},
+		{"empty slice", []record{}, ""},
+		{
+			"proto",
+			[]*examplepb.SimpleMessage{{Id: "foo"}, {Id: "bar"}},
+			`{"id":"foo"}` + "\n" + `{"id":"bar"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.Marshal(tt.v)
+			if err != nil {
+				t.Fatalf("m.Marshal(%v) failed with %v; want success", tt.v, err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("m.Marshal(%v) got = %q; want %q", tt.v, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("multiline compacted", func(t *testing.T) {
+		m := Codec{Marshaler: &jsonpb.Codec{}}
+		m.Marshaler.(*jsonpb.Codec).Multiline = true
+		got, err := m.Marshal(&examplepb.SimpleMessage{Id: "foo"})
+		if err != nil {
+			t.Fatalf("m.Marshal() failed with %v; want success", err)
+		}
+		if want := `{"id":"foo"}` + "\n"; string(got) != want {
+			t.Errorf("m.Marshal() got = %q; want %q", got, want)
+		}
+	})
+}
+
+func TestCodec_Unmarshal(t *testing.T) {
+	var m Codec
+
+	data := "{\"id\":1,\"name\":\"a\"}\r\n\n  \n{\"id\":2,\"name\":\"b\"}"
+
+	var got []record
+	if err := m.Unmarshal([]byte(data), &got); err != nil {
+		t.Fatalf("m.Unmarshal() failed with %v; want success", err)
+	}
+	if diff := cmp.Diff(got, []record{{1, "a"}, {2, "b"}}); diff != "" {
+		t.Error(diff)
+	}
+
+	var gotPtr []*record
+	if err := m.Unmarshal([]byte(data), &gotPtr); err != nil {
+		t.Fatalf("m.Unmarshal() failed with %v; want success", err)
+	}
+	if diff := cmp.Diff(gotPtr, []*record{{1, "a"}, {2, "b"}}); diff != "" {
+		t.Error(diff)
+	}
+
+	var one record
+	if err := m.Unmarshal([]byte(data), &one); err != nil {
+		t.Fatalf("m.Unmarshal() failed with %v; want success", err)
+	}
+	if one != (record{1, "a"}) {
+		t.Errorf("m.Unmarshal() got = %v", one)
+	}
+	if err := m.Unmarshal(nil, &one); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("m.Unmarshal() error = %v; want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	var msgs []*examplepb.SimpleMessage
+	if err := m.Unmarshal([]byte(`{"id":"foo"}`+"\n"+`{"id":"bar"}`), &msgs); err != nil {
+		t.Fatalf("m.Unmarshal() failed with %v; want success", err)
+	}
+	want := []*examplepb.SimpleMessage{{Id: "foo"}, {Id: "bar"}}
+	if diff := cmp.Diff(msgs, want, protocmp.Transform()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestCodec_DecodeFieldError(t *testing.T) {
+	var m Codec
+
+	var got []record
+	err := m.NewDecoder(strings.NewReader(`{"id":1}` + "\n\n" + `{"id":"x"}`)).Decode(&got)
+	var fe *codec.FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("Decode() error = %v; want *codec.FieldError", err)
+	}
+	if want := "id"; fe.Field != want {
+		t.Errorf("FieldError.Field got = %q; want %q", fe.Field, want)
+	}
+	if want := "line 3: "; !strings.HasPrefix(fe.Err.Error(), want) {
+		t.Errorf("FieldError.Err got = %q; want prefix %q", fe.Err, want)
+	}
+
+	err = m.NewDecoder(strings.NewReader(`{"id":1`)).Decode(&record{})
+	if !errors.As(err, &fe) || fe.Field != "" || !strings.HasPrefix(fe.Err.Error(), "line 1: ") {
+		t.Errorf("Decode() error = %v; want *codec.FieldError of line 1", err)
+	}
+}
+
+func TestCodec_DecodeReadError(t *testing.T) {
+	var m Codec
+	readErr := errors.New("read error")
+
+	dec := m.NewDecoder(io.MultiReader(strings.NewReader(`{"id":1}`), iotest.ErrReader(readErr)))
+	var got record
+	if err := dec.Decode(&got); err != nil || got.ID != 1 {
+		t.Fatalf("Decode() = %v, %v; want the last record", got, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := dec.Decode(&got); !errors.Is(err, readErr) {
+			t.Errorf("Decode() error = %v; want %v", err, readErr)
+		}
+	}
+}
+
+func TestCodec_MaxRecordSize(t *testing.T) {
+	m := Codec{MaxRecordSize: 16}
+
+	dec := m.NewDecoder(strings.NewReader(`{"id":1}` + "\n" + `{"id":2,"name":"too large"}` + "\n"))
+	var got record
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode() failed with %v; want success", err)
+	}
+	if err := dec.Decode(&got); !errors.Is(err, ErrRecordTooLarge) {
+		t.Errorf("Decode() error = %v; want %v", err, ErrRecordTooLarge)
+	}
+	if !errors.Is(ErrRecordTooLarge, codec.ErrTooLarge) {
+		t.Errorf("ErrRecordTooLarge should wrap %v", codec.ErrTooLarge)
+	}
+
+	// the rest of a record larger than the read buffer is never decoded as a record.
+	large := `{"id":3,"name":"` + strings.Repeat("x", 8192) + `"}`
+	dec = m.NewDecoder(strings.NewReader(large + "\n" + `{"id":4}` + "\n"))
+	for i := 0; i < 2; i++ {
+		if err := dec.Decode(&got); !errors.Is(err, ErrRecordTooLarge) {
+			t.Errorf("Decode() #%d error = %v; want %v", i, err, ErrRecordTooLarge)
+		}
+	}
+}
+
+func TestCodec_Stream(t *testing.T) {
+	var m Codec
+
+	var buf bytes.Buffer
+	enc := m.NewEncoder(&buf)
+	for i := 1; i <= 3; i++ {
+		if err := enc.Encode(&record{ID: i}); err != nil {
+			t.Fatalf("Encode() failed with %v; want success", err)
+		}
+	}
+
+	var got []int
+	codec.Iter[*record](m.NewDecoder(&buf))(func(r *record, err error) bool {
+		if err != nil {
+			t.Fatalf("Iter() failed with %v; want success", err)
+		}
+		got = append(got, r.ID)
+		return true
+	})
+	if diff := cmp.Diff(got, []int{1, 2, 3}); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestCodec_Iter(t *testing.T) {
+	var m Codec
+
+	t.Run("value", func(t *testing.T) {
+		var got []record
+		codec.Iter[record](m.NewDecoder(strings.NewReader(`{"id":1}` + "\n" + `{"id":2}`)))(func(r record, err error) bool {
+			if err != nil {
+				t.Fatalf("Iter() failed with %v; want success", err)
+			}
+			got = append(got, r)
+			return true
+		})
+		if diff := cmp.Diff(got, []record{{ID: 1}, {ID: 2}}); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("break", func(t *testing.T) {
+		n := 0
+		codec.Iter[*examplepb.SimpleMessage](m.NewDecoder(strings.NewReader(`{"id":"a"}` + "\n" + `{"id":"b"}`)))(
+			func(msg *examplepb.SimpleMessage, err error) bool {
+				n++
+				if msg.GetId() != "a" {
+					t.Errorf("Iter() got = %v", msg)
+				}
+				return false
+			})
+		if n != 1 {
+			t.Errorf("Iter() yields %d records after break; want 1", n)
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		var errs []error
+		codec.Iter[*record](m.NewDecoder(strings.NewReader(`{"id":1}` + "\n" + `bad` + "\n" + `{"id":3}`)))(
+			func(r *record, err error) bool {
+				errs = append(errs, err)
+				return true
+			})
+		if len(errs) != 2 || errs[0] != nil || errs[1] == nil {
+			t.Errorf("Iter() errors got = %v; want [nil, error]", errs)
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package encoding
+
+import (
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mediaRange is a parsed element of an `Accept` header, see RFC 9110 section 12.5.1.
+type mediaRange struct {
+	// mediaType is the lower-cased "type/subtype", "type/*" or "*/*".
+	mediaType string
+	// params holds the media type parameters, the "q" weight excluded.
+	params map[string]string
+	// q is the quality value in range [0, 1].
+	q float64
+	// index is the position of the media range in the header.
+	index int
+}
+
+// specificity returns how specific the media range is,
+// "*/*" < "type/*" < "type/subtype" < "type/subtype;param=value".
+func (m *mediaRange) specificity() int {
+	switch {
+	case m.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(m.mediaType, "/*"):
+		return 1
+	default:
+		return 2 + len(m.params)
+	}
+}
+
+// match reports whether the media range matches the media type, parameters are ignored.
+func (m *mediaRange) match(mediaType string) bool {
+	switch {
+	case m.mediaType == "*/*":
+		return true
+	case strings.HasSuffix(m.mediaType, "/*"):
+		return strings.HasPrefix(mediaType, m.mediaType[:len(m.mediaType)-1])
+	default:
+		return m.mediaType == mediaType
+	}
+}
+
+// parseAccept parses the `Accept` header values into media ranges,
+// ordered by quality and specificity, the most preferred first.
+// Invalid media ranges are ignored.
+func parseAccept(values []string) []*mediaRange {
+	var ranges []*mediaRange
+
+	for _, value := range values {
+		for _, s := range splitHeader(value) {
+			if s == "*" { // some clients send a bare "*".
+				s = "*/*"
+			}
+			mediaType, params, err := mime.ParseMediaType(s)
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if qv, ok := params["q"]; ok {
+				q, err = strconv.ParseFloat(qv, 64)
+				if err != nil || q < 0 || q > 1 {
+					continue
+				}
+				delete(params, "q")
+			}
+			if strings.HasPrefix(mediaType, "*/") && mediaType != "*/*" {
+				continue
+			}
+			ranges = append(ranges, &mediaRange{
+				mediaType: mediaType,
+				params:    params,
+				q:         q,
+				index:     len(ranges),
+			})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges
+}
+
+// quality returns the quality of the media type and the most specific media range matched it.
+// It returns nil media range if none matched.
+// A media range with parameters and "q=0" excludes only the media type with the parameters,
+// e.g. "application/json;v=2;q=0", so it is skipped.
+func quality(ranges []*mediaRange, mediaType string) (float64, *mediaRange) {
+	var matched *mediaRange
+
+	for _, rg := range ranges {
+		if !rg.match(mediaType) || (rg.q == 0 && len(rg.params) > 0) {
+			continue
+		}
+		if matched == nil ||
+			rg.specificity() > matched.specificity() ||
+			(rg.specificity() == matched.specificity() && rg.index < matched.index) {
+			matched = rg
+		}
+	}
+	if matched == nil {
+		return 0, nil
+	}
+	return matched.q, matched
+}
+
+// splitHeader splits a comma-separated header value, commas in quoted strings are kept.
+func splitHeader(header string) []string {
+	var values []string
+	var quoted, escaped bool
+
+	start := 0
+	for i := 0; i < len(header); i++ {
+		c := header[i]
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == ',':
+			if v := strings.TrimSpace(header[start:i]); v != "" {
+				values = append(values, v)
+			}
+			start = i + 1
+		}
+	}
+	if v := strings.TrimSpace(header[start:]); v != "" {
+		values = append(values, v)
+	}
+	return values
+}
+
+// baseMediaType returns the media type without parameters, or empty if invalid.
+func baseMediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
@@ -0,0 +1,248 @@
+package encoding
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/things-go/encoding/json"
+	"github.com/things-go/encoding/xml"
+	"github.com/things-go/encoding/yaml"
+)
+
+func Test_SplitHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []string
+	}{
+		{
+			"",
+			"application/json, text/plain, */*",
+			[]string{"application/json", "text/plain", "*/*"},
+		},
+		{
+			"",
+			"application/json,text/plain,   */*",
+			[]string{"application/json", "text/plain", "*/*"},
+		},
+		{
+			"quoted comma",
+			`text/plain;foo="a,b", application/json`,
+			[]string{`text/plain;foo="a,b"`, "application/json"},
+		},
+		{
+			"empty element",
+			"application/json,, ",
+			[]string{"application/json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitHeader(tt.header); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitHeader() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ParseAccept(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []string
+	}{
+		{
+			"header order",
+			[]string{"application/json, text/plain, */*"},
+			[]string{"application/json", "text/plain", "*/*"},
+		},
+		{
+			"spaces trimmed",
+			[]string{"application/json,text/plain,   */*"},
+			[]string{"application/json", "text/plain", "*/*"},
+		},
+		{
+			"ordered by quality",
+			[]string{"application/xml;q=0.1, application/json;q=0.9"},
+			[]string{"application/json", "application/xml"},
+		},
+		{
+			"ordered by specificity",
+			[]string{"*/*, application/*, application/json;v=1, application/json"},
+			[]string{"application/json", "application/json", "application/*", "*/*"},
+		},
+		{
+			"multiple headers",
+			[]string{"text/plain;q=0.5", "application/json"},
+			[]string{"application/json", "text/plain"},
+		},
+		{
+			"invalid ignored",
+			[]string{"application/json;q=2, */json, text/plain;q=abc, ;, text/html"},
+			[]string{"text/html"},
+		},
+		{
+			"bare wildcard",
+			[]string{"*"},
+			[]string{"*/*"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, rg := range parseAccept(tt.values) {
+				got = append(got, rg.mediaType)
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_Encoding_OutboundForRequest_Negotiate(t *testing.T) {
+	registry := New()
+	require.NoError(t, registry.Register(MIMEXML, &xml.Codec{}))
+	require.NoError(t, registry.Register(MIMEXML2, &xml.Codec{}))
+	require.NoError(t, registry.Register(MIMEYAML, &yaml.Codec{}))
+
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+	}{
+		{"empty", "", MIMEWildcard},
+		{"quality", "application/xml;q=0.1, application/json;q=0.9", MIMEJSON},
+		{"parameters", "application/json; charset=utf-8", MIMEJSON},
+		{"header order", "text/xml, application/xml", MIMEXML2},
+		{"specificity", "*/*;q=0.8, application/x-yaml;q=0.8", MIMEYAML},
+		{"any", "*/*", MIMEWildcard},
+		{"type wildcard", "text/*", MIMEXML2},
+		{"type wildcard prefer wildcard marshaler", "application/*", MIMEWildcard},
+		{"exclusion", "application/json;q=0, application/xml;q=0.5, */*;q=0.1", MIMEXML},
+		{"exclusion by type", "application/*;q=0, text/*;q=0.5", MIMEXML2},
+		{"all excluded", "*/*;q=0", MIMEWildcard},
+		{"exclusion with parameters", "application/json;v=2;q=0, application/json", MIMEJSON},
+		{"form exactly", "application/x-www-form-urlencoded", MIMEPOSTForm},
+		{"unknown", "application/unknown", MIMEWildcard},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			r.Header.Set("Accept", tt.accept)
+			got, marshaler, err := registry.NegotiateOutbound(r)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.NotNil(t, marshaler)
+			require.Same(t, marshaler, registry.OutboundForRequest(r))
+		})
+	}
+	t.Run("forms are not accepted by any", func(t *testing.T) {
+		registry := New()
+		for _, accept := range []string{"application/json;q=0, */*", "application/json;q=0, application/*"} {
+			r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			r.Header.Set("Accept", accept)
+			got, marshaler, err := registry.NegotiateOutbound(r)
+			require.NoError(t, err)
+			require.Equal(t, MIMEWildcard, got, accept)
+			require.IsType(t, &json.Codec{}, marshaler)
+		}
+	})
+}
+
+func Test_Encoding_Render_ContentType(t *testing.T) {
+	registry := New()
+	require.NoError(t, registry.Register(MIMEXML, &xml.Codec{}))
+	require.NoError(t, registry.Register(MIMEXML2, &xml.Codec{}))
+	require.NoError(t, registry.Register("application/vnd.foo", &json.Codec{}))
+
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+	}{
+		{"wildcard", "", "application/json; charset=utf-8"},
+		{"registered", "application/xml", "application/xml; charset=utf-8"},
+		{"alias", "text/xml", "text/xml; charset=utf-8"},
+		{"vendor", "application/vnd.foo", "application/vnd.foo; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			r.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			err := registry.Render(w, r, &TestMode{Id: "foo", Name: "bar"})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, w.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func Test_StructuredSyntaxSuffix(t *testing.T) {
+	tests := []struct {
+		mime string
+		want string
+	}{
+		{"application/problem+json", SuffixJSON},
+		{"application/vnd.acme.order.v2+json", SuffixJSON},
+		{"application/soap+xml", SuffixXML},
+		{"application/json", ""},
+		{"application/+json", ""},
+		{"application/foo+", ""},
+		{"+json", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mime, func(t *testing.T) {
+			require.Equal(t, tt.want, structuredSyntaxSuffix(tt.mime))
+		})
+	}
+}
+
+func Test_Encoding_StructuredSyntaxSuffix(t *testing.T) {
+	registry := New()
+	xmlCodec := &xml.Codec{}
+	yamlCodec := &yaml.Codec{}
+	require.NoError(t, registry.Register(MIMEXML, xmlCodec))
+	require.NoError(t, registry.Register(SuffixYAML, yamlCodec))
+	require.Error(t, registry.Register("+", yamlCodec))
+
+	t.Run("inbound", func(t *testing.T) {
+		tests := []struct {
+			contentType string
+			want        any
+		}{
+			{"application/vnd.acme.order.v2+json", registry.Get(MIMEJSON)},
+			{"application/merge-patch+json; charset=utf-8", registry.Get(MIMEJSON)},
+			{"application/atom+xml", xmlCodec},
+			{"application/vnd.foo+yaml", yamlCodec},
+		}
+		for _, tt := range tests {
+			t.Run(tt.contentType, func(t *testing.T) {
+				r := httptest.NewRequest(http.MethodPost, "http://example.com", nil)
+				r.Header.Set("Content-Type", tt.contentType)
+				contentType, m := registry.InboundForRequest(r)
+				require.Equal(t, baseMediaType(tt.contentType), contentType)
+				require.Same(t, tt.want, m)
+			})
+		}
+	})
+	t.Run("not found", func(t *testing.T) {
+		registry := New().SetStrict(true)
+		_, err := registry.Lookup("application/vnd.foo+cbor")
+		require.ErrorIs(t, err, ErrUnsupportedMediaType)
+	})
+	t.Run("outbound", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		r.Header.Set("Accept", "application/problem+json, application/json;q=0.5")
+		mediaType, m, err := registry.NegotiateOutbound(r)
+		require.NoError(t, err)
+		require.Equal(t, "application/problem+json", mediaType)
+		require.Same(t, registry.Get(MIMEJSON), m)
+
+		w := httptest.NewRecorder()
+		require.NoError(t, registry.Render(w, r, &TestMode{Id: "foo"}))
+		require.Equal(t, "application/problem+json; charset=utf-8", w.Header().Get("Content-Type"))
+	})
+}
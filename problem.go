@@ -0,0 +1,449 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/things-go/encoding/codec"
+	jsoncodec "github.com/things-go/encoding/json"
+)
+
+// Problem Details MIME types, see RFC 9457.
+const (
+	MIMEProblemJSON = "application/problem+json"
+	MIMEProblemXML  = "application/problem+xml"
+)
+
+// problemXMLNamespace is the XML namespace of the problem details, see RFC 9457 appendix B.
+const problemXMLNamespace = "urn:ietf:rfc:7807"
+
+// extensionInvalidParams is the extension member of the invalid parameters.
+const extensionInvalidParams = "invalid-params"
+
+// Problem is a Problem Details object, see RFC 9457.
+// It implements error and ProblemError, so a handler can return it directly.
+type Problem struct {
+	// Type is a URI reference that identifies the problem type,
+	// it is "about:blank" if not set.
+	Type string `json:"type,omitempty"`
+	// Title is a short, human-readable summary of the problem type,
+	// it is the status text of Status if not set.
+	Title string `json:"title,omitempty"`
+	// Status is the HTTP status code, it is 500 if not set.
+	Status int `json:"status,omitempty"`
+	// Detail is a human-readable explanation specific to this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// Instance is a URI reference that identifies the specific occurrence of the problem.
+	Instance string `json:"instance,omitempty"`
+	// Extensions is the extension members, the members which conflict
+	// with the above standard members are ignored.
+	Extensions map[string]any `json:"-"`
+}
+
+// InvalidParam is an invalid request parameter, the decode failures are reported
+// in the "invalid-params" extension member of the Problem.
+type InvalidParam struct {
+	// Name is the name of the parameter, it is empty if the whole source is invalid.
+	Name string `json:"name" xml:"name"`
+	// Reason is why the parameter is invalid.
+	Reason string `json:"reason" xml:"reason"`
+	// Source is where the parameter comes from, e.g. "body", "query".
+	Source string `json:"source,omitempty" xml:"source,omitempty"`
+}
+
+// ProblemError is implemented by the errors which report their own Problem.
+type ProblemError interface {
+	error
+	Problem() *Problem
+}
+
+// ProblemMapper maps an error to a Problem, it returns nil if the error is not handled.
+type ProblemMapper func(err error) *Problem
+
+func (p *Problem) Error() string {
+	title := p.Title
+	if title == "" {
+		title = http.StatusText(p.Status)
+	}
+	if p.Detail == "" {
+		return title
+	}
+	return title + ": " + p.Detail
+}
+
+// Problem returns itself.
+func (p *Problem) Problem() *Problem { return p }
+
+// problemMembers is the standard members of the Problem.
+var problemMembers = map[string]bool{
+	"type":     true,
+	"title":    true,
+	"status":   true,
+	"detail":   true,
+	"instance": true,
+}
+
+// problem has the fields but not the methods of Problem.
+type problem Problem
+
+// MarshalJSON implements json.Marshaler, the extension members are at the top level.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(problem(p))
+	if err != nil {
+		return nil, err
+	}
+	names := p.extensionNames()
+	if len(names) == 0 {
+		return b, nil
+	}
+	buf := bytes.NewBuffer(b[:len(b)-1])
+	for i, name := range names {
+		value, err := json.Marshal(p.Extensions[name])
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 || len(b) > 2 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, the members other than
+// the standard members are decoded into the Extensions.
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	var std problem
+	if err := json.Unmarshal(data, &std); err != nil {
+		return err
+	}
+	*p = Problem(std)
+	for name, raw := range members {
+		if problemMembers[name] {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+		if p.Extensions == nil {
+			p.Extensions = make(map[string]any)
+		}
+		p.Extensions[name] = value
+	}
+	return nil
+}
+
+// MarshalXML implements xml.Marshaler, see RFC 9457 appendix B.
+// The root element is always "problem" in the "urn:ietf:rfc:7807" namespace,
+// the extension members are child elements, slices are written as
+// the "i" elements and maps as the child elements with the map keys.
+func (p Problem) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	start := xml.StartElement{Name: xml.Name{Space: problemXMLNamespace, Local: "problem"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, member := range []struct{ name, value string }{
+		{"type", p.Type},
+		{"title", p.Title},
+		{"status", statusString(p.Status)},
+		{"detail", p.Detail},
+		{"instance", p.Instance},
+	} {
+		if member.value == "" {
+			continue
+		}
+		if err := e.EncodeElement(member.value, xml.StartElement{Name: xml.Name{Local: member.name}}); err != nil {
+			return err
+		}
+	}
+	for _, name := range p.extensionNames() {
+		if err := encodeXMLMember(e, name, p.Extensions[name]); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func statusString(status int) string {
+	if status == 0 {
+		return ""
+	}
+	return strconv.Itoa(status)
+}
+
+// extensionNames returns the sorted extension member names, the standard members excluded.
+func (p *Problem) extensionNames() []string {
+	names := make([]string, 0, len(p.Extensions))
+	for name := range p.Extensions {
+		if !problemMembers[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// encodeXMLMember writes the value as the element name.
+func encodeXMLMember(e *xml.Encoder, name string, v any) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			break
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Invalid, reflect.Pointer, reflect.Interface:
+		return e.EncodeElement("", start)
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := encodeXMLMember(e, "i", rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := encodeXMLMember(e, key.String(), rv.MapIndex(key).Interface()); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	}
+	return e.EncodeElement(rv.Interface(), start)
+}
+
+// RenderError writes err as a Problem Details (RFC 9457) response.
+// err is mapped to a Problem by the ProblemMapper registered by RegisterProblemMapper,
+// the latest registered first, then the ProblemError it wraps, otherwise the built-in mapping:
+//
+//	ErrNotAcceptable --> 406 Not Acceptable
+//	ErrUnsupportedMediaType, ErrUnsupportedContentEncoding --> 415 Unsupported Media Type
+//	ErrBodyTooLarge (*LimitError), codec.ErrTooLarge --> 413 Content Too Large
+//	ErrReadTimeout --> 408 Request Timeout
+//	*codec.FieldError, codec.FieldErrors --> 400 Bad Request, with the "invalid-params"
+//	others --> 500 Internal Server Error, without the detail
+//
+// The response format is negotiated by the `Accept` header with the registry like Render,
+// but never reports ErrNotAcceptable:
+//
+//	protobuf (MIMEPROTOBUF, "+proto" and "+protobuf") --> google.rpc.Status, the
+//		"invalid-params" is in a google.rpc.BadRequest detail
+//	XML (MIMEXML, MIMEXML2 and "+xml") --> MIMEProblemXML
+//	others --> MIMEProblemJSON
+//
+// The MIMEProblemXML and MIMEProblemJSON are encoded by the marshalers looked up by them,
+// the MIMEProblemJSON falls back to json.Codec if not found.
+// It writes nothing if err is nil.
+func (r *Encoding) RenderError(w http.ResponseWriter, req *http.Request, err error) error {
+	if err == nil {
+		return nil
+	}
+	reg := r.load()
+	p := reg.problemFor(err)
+	contentType, body, err := reg.encodeProblem(req, p)
+	if err != nil {
+		return err
+	}
+	header := w.Header()
+	header.Set(contentTypeHeader, contentType)
+	header.Set(contentLengthHeader, strconv.Itoa(len(body)))
+	addVary(header, acceptHeader)
+	header.Del(contentEncodingHeader)
+	w.WriteHeader(p.Status)
+	_, err = w.Write(body)
+	return err
+}
+
+// problemFor maps err to a Problem with the defaults filled.
+func (r *registry) problemFor(err error) *Problem {
+	var p *Problem
+	for i := len(r.problemMappers) - 1; i >= 0 && p == nil; i-- {
+		p = r.problemMappers[i](err)
+	}
+	if p == nil {
+		var pe ProblemError
+		if errors.As(err, &pe) {
+			p = pe.Problem()
+		}
+	}
+	if p == nil {
+		p = defaultProblem(err)
+	}
+	c := *p
+	if c.Status == 0 {
+		c.Status = http.StatusInternalServerError
+	}
+	if c.Type == "" {
+		c.Type = "about:blank"
+	}
+	if c.Title == "" {
+		c.Title = http.StatusText(c.Status)
+	}
+	return &c
+}
+
+// defaultProblem is the built-in mapping of the errors, see RenderError.
+func defaultProblem(err error) *Problem {
+	var mediaTypeErr *MediaTypeError
+	var encodingErr *ContentEncodingError
+
+	p := &Problem{Detail: err.Error()}
+	switch {
+	case errors.Is(err, ErrNotAcceptable):
+		p.Status = http.StatusNotAcceptable
+	case errors.Is(err, ErrUnsupportedMediaType), errors.Is(err, ErrUnsupportedContentEncoding):
+		p.Status = http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrBodyTooLarge), errors.Is(err, codec.ErrTooLarge):
+		p.Status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrReadTimeout):
+		p.Status = http.StatusRequestTimeout
+	default:
+		fes := codec.AsFieldErrors(err)
+		if len(fes) == 0 {
+			return &Problem{Status: http.StatusInternalServerError}
+		}
+		params := make([]InvalidParam, 0, len(fes))
+		for _, fe := range fes {
+			reason := fe.Error()
+			if fe.Err != nil {
+				reason = fe.Err.Error()
+			}
+			params = append(params, InvalidParam{Name: fe.Field, Reason: reason, Source: fe.Source})
+		}
+		p.Status = http.StatusBadRequest
+		p.Extensions = map[string]any{extensionInvalidParams: params}
+		return p
+	}
+	switch {
+	case errors.As(err, &mediaTypeErr):
+		p.Extensions = map[string]any{"supported": mediaTypeErr.Supported}
+	case errors.As(err, &encodingErr):
+		p.Extensions = map[string]any{"supported": encodingErr.Supported}
+	}
+	return p
+}
+
+// encodeProblem returns the `Content-Type` and body of the Problem for this request,
+// see RenderError.
+func (r *registry) encodeProblem(req *http.Request, p *Problem) (string, []byte, error) {
+	mediaType, marshaler := r.marshalerFromHeaderAccept(req.Header[acceptHeader])
+	if marshaler == nil {
+		mediaType, marshaler = MIMEWildcard, r.mimeWildcard
+	}
+	if mediaType == MIMEWildcard {
+		mediaType = baseMediaType(marshaler.ContentType(nil))
+	}
+	switch suffix := structuredSyntaxSuffix(mediaType); {
+	case mediaType == MIMEPROTOBUF || suffix == SuffixPROTO || suffix == SuffixPROTOBUF:
+		// encoded by the Encoder, so the status is framed like the other messages,
+		// e.g. "application/x-protobuf; delimited=true".
+		st := p.rpcStatus()
+		var body bytes.Buffer
+		if err := marshaler.NewEncoder(&body).Encode(st); err != nil {
+			return "", nil, err
+		}
+		return contentTypeFor(mediaType, marshaler, st), body.Bytes(), nil
+	case mediaType == MIMEXML || mediaType == MIMEXML2 || suffix == SuffixXML:
+		if m := r.lookup(MIMEProblemXML); m != nil {
+			marshaler = m
+		}
+		body, err := marshaler.Marshal(p)
+		if err != nil {
+			return "", nil, err
+		}
+		return contentTypeFor(MIMEProblemXML, marshaler, p), body, nil
+	default:
+		marshaler = r.lookup(MIMEProblemJSON)
+		if marshaler == nil {
+			marshaler = &jsoncodec.Codec{}
+		}
+		body, err := marshaler.Marshal(p)
+		if err != nil {
+			return "", nil, err
+		}
+		return contentTypeFor(MIMEProblemJSON, marshaler, p), body, nil
+	}
+}
+
+// rpcCodes maps the HTTP status to the google.rpc.Code,
+// it is the inverse of the HTTP mapping in google/rpc/code.proto.
+var rpcCodes = map[int]int32{
+	http.StatusOK:                    0,  // OK
+	499:                              1,  // CANCELLED
+	http.StatusBadRequest:            3,  // INVALID_ARGUMENT
+	http.StatusNotAcceptable:         3,  // INVALID_ARGUMENT
+	http.StatusUnsupportedMediaType:  3,  // INVALID_ARGUMENT
+	http.StatusRequestTimeout:        4,  // DEADLINE_EXCEEDED
+	http.StatusGatewayTimeout:        4,  // DEADLINE_EXCEEDED
+	http.StatusNotFound:              5,  // NOT_FOUND
+	http.StatusConflict:              10, // ABORTED
+	http.StatusForbidden:             7,  // PERMISSION_DENIED
+	http.StatusRequestEntityTooLarge: 8,  // RESOURCE_EXHAUSTED
+	http.StatusTooManyRequests:       8,  // RESOURCE_EXHAUSTED
+	http.StatusPreconditionFailed:    9,  // FAILED_PRECONDITION
+	http.StatusNotImplemented:        12, // UNIMPLEMENTED
+	http.StatusInternalServerError:   13, // INTERNAL
+	http.StatusServiceUnavailable:    14, // UNAVAILABLE
+	http.StatusUnauthorized:          16, // UNAUTHENTICATED
+}
+
+// rpcStatus returns the google.rpc.Status of the Problem,
+// the unmapped HTTP status is UNKNOWN.
+func (p *Problem) rpcStatus() *status.Status {
+	code, ok := rpcCodes[p.Status]
+	if !ok {
+		code = 2 // UNKNOWN
+	}
+	st := &status.Status{Code: code, Message: p.Detail}
+	if st.Message == "" {
+		st.Message = p.Title
+	}
+	if params, ok := p.Extensions[extensionInvalidParams].([]InvalidParam); ok && len(params) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, param := range params {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       param.Name,
+				Description: param.Reason,
+			})
+		}
+		if detail, err := anypb.New(br); err == nil {
+			st.Details = append(st.Details, detail)
+		}
+	}
+	return st
+}
@@ -0,0 +1,249 @@
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/things-go/encoding/codec"
+	pro "github.com/things-go/encoding/proto"
+	"github.com/things-go/encoding/xml"
+)
+
+func Test_Problem_JSON(t *testing.T) {
+	p := &Problem{
+		Type:   "https://example.com/probs/out-of-credit",
+		Title:  "You do not have enough credit.",
+		Status: http.StatusForbidden,
+		Extensions: map[string]any{
+			"balance": 30,
+			"title":   "ignored",
+			"account": "/account/12345",
+		},
+	}
+	b, err := p.MarshalJSON()
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"type": "https://example.com/probs/out-of-credit",
+		"title": "You do not have enough credit.",
+		"status": 403,
+		"account": "/account/12345",
+		"balance": 30
+	}`, string(b))
+
+	var got Problem
+	require.NoError(t, got.UnmarshalJSON(b))
+	require.Equal(t, p.Type, got.Type)
+	require.Equal(t, p.Title, got.Title)
+	require.Equal(t, p.Status, got.Status)
+	require.Equal(t, map[string]any{"balance": float64(30), "account": "/account/12345"}, got.Extensions)
+
+	b, err = (&Problem{Extensions: map[string]any{"a": 1}}).MarshalJSON()
+	require.NoError(t, err)
+	require.JSONEq(t, `{"a":1}`, string(b))
+}
+
+func Test_Problem_XML(t *testing.T) {
+	p := &Problem{
+		Type:   "about:blank",
+		Title:  "Bad Request",
+		Status: http.StatusBadRequest,
+		Extensions: map[string]any{
+			"invalid-params": []InvalidParam{{Name: "age", Reason: "must be positive"}},
+			"limits":         map[string]int{"max": 10},
+		},
+	}
+	b, err := (&xml.Codec{}).Marshal(p)
+	require.NoError(t, err)
+	require.Equal(t,
+		`<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type><title>Bad Request</title><status>400</status>`+
+			`<invalid-params><i><name>age</name><reason>must be positive</reason></i></invalid-params>`+
+			`<limits><max>10</max></limits></problem>`,
+		string(b))
+}
+
+func Test_Encoding_RenderError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			"not acceptable",
+			&MediaTypeError{Err: ErrNotAcceptable, MediaType: "text/csv", Supported: []string{MIMEJSON}},
+			http.StatusNotAcceptable,
+			`{"type":"about:blank","title":"Not Acceptable","status":406,
+				"detail":"encoding: not acceptable: \"text/csv\", supported: [application/json]",
+				"supported":["application/json"]}`,
+		},
+		{
+			"unsupported content encoding",
+			fmt.Errorf("bind: %w", &ContentEncodingError{ContentEncoding: "br", Supported: []string{EncodingGzip}}),
+			http.StatusUnsupportedMediaType,
+			`{"type":"about:blank","title":"Unsupported Media Type","status":415,
+				"detail":"bind: encoding: unsupported content encoding: \"br\", supported: [gzip]",
+				"supported":["gzip"]}`,
+		},
+		{
+			"body too large",
+			&LimitError{Limit: "MaxBodySize", Max: 10},
+			http.StatusRequestEntityTooLarge,
+			`{"type":"about:blank","title":"Request Entity Too Large","status":413,
+				"detail":"encoding: request body too large: exceeds MaxBodySize(10)"}`,
+		},
+		{
+			"message too large",
+			(&pro.Codec{Delimited: true, MaxMessageSize: 1}).NewDecoder(strings.NewReader("\x02ab")).Decode(&status.Status{}),
+			http.StatusRequestEntityTooLarge,
+			`{"type":"about:blank","title":"Request Entity Too Large","status":413,
+				"detail":"proto: message too large: 2 bytes exceeds 1 bytes"}`,
+		},
+		{
+			"read timeout",
+			ErrReadTimeout,
+			http.StatusRequestTimeout,
+			`{"type":"about:blank","title":"Request Timeout","status":408,
+				"detail":"encoding: request body read timeout"}`,
+		},
+		{
+			"field errors",
+			codec.FieldErrors{
+				{Source: codec.SourceQuery, Field: "age", Value: "x", Err: errors.New("invalid syntax")},
+				{Source: codec.SourceBody, Err: errors.New("unexpected EOF")},
+			},
+			http.StatusBadRequest,
+			`{"type":"about:blank","title":"Bad Request","status":400,
+				"detail":"decode query field \"age\" value \"x\": invalid syntax; decode body: unexpected EOF",
+				"invalid-params":[
+					{"name":"age","reason":"invalid syntax","source":"query"},
+					{"name":"","reason":"unexpected EOF","source":"body"}
+				]}`,
+		},
+		{
+			"internal",
+			errors.New("database is down"),
+			http.StatusInternalServerError,
+			`{"type":"about:blank","title":"Internal Server Error","status":500}`,
+		},
+		{
+			"problem error",
+			fmt.Errorf("wrapped: %w", &Problem{Status: http.StatusNotFound, Detail: "no such user"}),
+			http.StatusNotFound,
+			`{"type":"about:blank","title":"Not Found","status":404,"detail":"no such user"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", MIMEJSON)
+			w := httptest.NewRecorder()
+			require.NoError(t, New().RenderError(w, req, tt.err))
+			require.Equal(t, tt.wantStatus, w.Code)
+			require.Equal(t, "application/problem+json; charset=utf-8", w.Header().Get("Content-Type"))
+			require.JSONEq(t, tt.wantBody, w.Body.String())
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		require.NoError(t, New().RenderError(w, httptest.NewRequest(http.MethodGet, "/", nil), nil))
+		require.Equal(t, 0, w.Body.Len())
+		require.Empty(t, w.Header())
+	})
+}
+
+func Test_Encoding_RenderError_ProblemMapper(t *testing.T) {
+	errOutOfCredit := errors.New("out of credit")
+
+	r := New()
+	require.Error(t, r.RegisterProblemMapper(nil))
+	require.NoError(t, r.RegisterProblemMapper(func(err error) *Problem {
+		return &Problem{Status: http.StatusTeapot}
+	}))
+	require.NoError(t, r.RegisterProblemMapper(func(err error) *Problem {
+		if !errors.Is(err, errOutOfCredit) {
+			return nil
+		}
+		return &Problem{
+			Type:       "https://example.com/probs/out-of-credit",
+			Title:      "You do not have enough credit.",
+			Status:     http.StatusForbidden,
+			Extensions: map[string]any{"balance": 30},
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	require.NoError(t, r.RenderError(w, req, fmt.Errorf("pay: %w", errOutOfCredit)))
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.JSONEq(t, `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.",
+		"status":403,"balance":30}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	require.NoError(t, r.RenderError(w, req, errors.New("other")))
+	require.Equal(t, http.StatusTeapot, w.Code)
+	require.JSONEq(t, `{"type":"about:blank","title":"I'm a teapot","status":418}`, w.Body.String())
+}
+
+func Test_Encoding_RenderError_Negotiate(t *testing.T) {
+	r := New().SetStrict(true)
+	_ = r.Register(MIMEXML, &xml.Codec{})
+	_ = r.Register(MIMEXML2, &xml.Codec{})
+	_ = r.Register(MIMEPROTOBUF, &pro.Codec{})
+
+	err := codec.FieldErrors{{Source: codec.SourceBody, Field: "age", Err: errors.New("invalid syntax")}}
+
+	t.Run("xml", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "text/xml")
+		w := httptest.NewRecorder()
+		require.NoError(t, r.RenderError(w, req, err))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Equal(t, "application/problem+xml; charset=utf-8", w.Header().Get("Content-Type"))
+		require.True(t, strings.HasPrefix(w.Body.String(), `<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type>`))
+		require.Contains(t, w.Body.String(), `<invalid-params><i><name>age</name><reason>invalid syntax</reason><source>body</source></i></invalid-params>`)
+	})
+	t.Run("problem+json", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "application/problem+json, application/xml;q=0.5")
+		w := httptest.NewRecorder()
+		require.NoError(t, r.RenderError(w, req, err))
+		require.Equal(t, "application/problem+json; charset=utf-8", w.Header().Get("Content-Type"))
+	})
+	t.Run("not acceptable falls back to json", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "text/csv")
+		w := httptest.NewRecorder()
+		require.NoError(t, r.RenderError(w, req, err))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Equal(t, "application/problem+json; charset=utf-8", w.Header().Get("Content-Type"))
+	})
+	t.Run("protobuf", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", MIMEPROTOBUF)
+		w := httptest.NewRecorder()
+		require.NoError(t, r.RenderError(w, req, err))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Equal(t, MIMEPROTOBUF, w.Header().Get("Content-Type"))
+
+		var st status.Status
+		require.NoError(t, proto.Unmarshal(w.Body.Bytes(), &st))
+		require.Equal(t, int32(3), st.Code)
+		require.Equal(t, err.Error(), st.Message)
+		require.Len(t, st.Details, 1)
+		var br errdetails.BadRequest
+		require.NoError(t, st.Details[0].UnmarshalTo(&br))
+		require.Len(t, br.FieldViolations, 1)
+		require.Equal(t, "age", br.FieldViolations[0].Field)
+		require.Equal(t, "invalid syntax", br.FieldViolations[0].Description)
+	})
+}
@@ -1,45 +1,111 @@
 package proto
 
 import (
+	"bufio"
 	"errors"
+	"fmt"
 	"io"
+	"strconv"
 
+	"google.golang.org/protobuf/encoding/protodelim"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/things-go/encoding/codec"
 )
 
+// ErrMessageTooLarge is reported when a message exceeds the Codec.MaxMessageSize,
+// it wraps codec.ErrTooLarge.
+var ErrMessageTooLarge = codec.NewTooLargeError("proto: message too large")
+
 // Codec is a Marshaller which marshals/unmarshals into/from serialize proto bytes
-type Codec struct{}
+// with the "google.golang.org/protobuf/proto" options, e.g. Deterministic, DiscardUnknown,
+// Resolver and RecursionLimit, which apply to the Marshal, Unmarshal, Encoder and Decoder.
+// AllowPartial is in both options, set it by c.MarshalOptions.AllowPartial and
+// c.UnmarshalOptions.AllowPartial, like jsonpb.Codec and prototext.Codec.
+// The decode failures are reported as *codec.FieldError, whose Field is empty since the
+// proto decoder does not report the fields, the Type is the message name.
+type Codec struct {
+	proto.MarshalOptions
+	proto.UnmarshalOptions
+	// Delimited enables the varint length-delimited framing, compatible with protodelim,
+	// for the Encoder and Decoder, so a stream of messages can round-trip.
+	// The Content-Type is "application/x-protobuf; delimited=true" if enabled.
+	Delimited bool
+	// MaxMessageSize is the maximum size in bytes of a message to decode,
+	// 0 means no limit.
+	MaxMessageSize int
+}
 
-// ContentType always returns "application/x-protobuf".
-func (*Codec) ContentType(_ any) string {
+// ContentType returns "application/x-protobuf",
+// or "application/x-protobuf; delimited=true" if Delimited.
+func (c *Codec) ContentType(_ any) string {
+	if c.Delimited {
+		return "application/x-protobuf; delimited=true"
+	}
 	return "application/x-protobuf"
 }
-func (*Codec) Marshal(value any) ([]byte, error) {
+
+// WithParams returns the Codec with Delimited set by the "delimited" parameter,
+// it implements codec.ParamsMarshaler.
+func (c *Codec) WithParams(params map[string]string) codec.Marshaler {
+	v, ok := params["delimited"]
+	if !ok {
+		return c
+	}
+	delimited, err := strconv.ParseBool(v)
+	if err != nil || delimited == c.Delimited {
+		return c
+	}
+	cc := *c
+	cc.Delimited = delimited
+	return &cc
+}
+
+func (c *Codec) Marshal(value any) ([]byte, error) {
 	message, ok := value.(proto.Message)
 	if !ok {
 		return nil, errors.New("unable to marshal non proto field")
 	}
-	return proto.Marshal(message)
+	return c.MarshalOptions.Marshal(message)
 }
-func (*Codec) Unmarshal(data []byte, value any) error {
+func (c *Codec) Unmarshal(data []byte, value any) error {
 	message, ok := value.(proto.Message)
 	if !ok {
 		return errors.New("unable to unmarshal non proto field")
 	}
-	return proto.Unmarshal(data, message)
+	if err := c.UnmarshalOptions.Unmarshal(data, message); err != nil {
+		return &codec.FieldError{Type: string(message.ProtoReflect().Descriptor().FullName()), Err: err}
+	}
+	return nil
 }
+
+// NewDecoder returns a Decoder which reads the whole "r" as a message,
+// or a length-delimited message per Decode if Delimited.
 func (c *Codec) NewDecoder(r io.Reader) codec.Decoder {
+	if c.Delimited {
+		return c.NewStreamDecoder(r)
+	}
+	if c.MaxMessageSize > 0 {
+		r = io.LimitReader(r, int64(c.MaxMessageSize)+1)
+	}
 	return codec.DecoderFunc(func(value any) error {
 		buffer, err := io.ReadAll(r)
 		if err != nil {
 			return err
 		}
+		if c.MaxMessageSize > 0 && len(buffer) > c.MaxMessageSize {
+			return fmt.Errorf("%w: exceeds %d bytes", ErrMessageTooLarge, c.MaxMessageSize)
+		}
 		return c.Unmarshal(buffer, value)
 	})
 }
+
+// NewEncoder returns an Encoder which writes the messages into "w",
+// each message is length-delimited if Delimited.
 func (c *Codec) NewEncoder(w io.Writer) codec.Encoder {
+	if c.Delimited {
+		return c.NewStreamEncoder(w)
+	}
 	return codec.EncoderFunc(func(value any) error {
 		buffer, err := c.Marshal(value)
 		if err != nil {
@@ -49,3 +115,79 @@ func (c *Codec) NewEncoder(w io.Writer) codec.Encoder {
 		return err
 	})
 }
+
+// NewStreamEncoder returns a StreamEncoder which writes the length-delimited messages into "w",
+// regardless of Delimited, it implements codec.StreamMarshaler.
+func (c *Codec) NewStreamEncoder(w io.Writer) codec.StreamEncoder {
+	return &delimitedEncoder{w: w, opts: protodelim.MarshalOptions{MarshalOptions: c.MarshalOptions}}
+}
+
+// NewStreamDecoder returns a Decoder which reads the length-delimited messages from "r",
+// regardless of Delimited, it implements codec.StreamMarshaler.
+// It returns io.EOF at the end of the stream, and io.ErrUnexpectedEOF
+// if the stream ends in the middle of a message.
+func (c *Codec) NewStreamDecoder(r io.Reader) codec.Decoder {
+	opts := protodelim.UnmarshalOptions{UnmarshalOptions: c.UnmarshalOptions, MaxSize: -1}
+	if c.MaxMessageSize > 0 {
+		opts.MaxSize = int64(c.MaxMessageSize)
+	}
+	d := &delimitedDecoder{src: r, opts: opts}
+	d.r = bufio.NewReader(readerFunc(d.read))
+	return d
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
+
+type delimitedEncoder struct {
+	w    io.Writer
+	opts protodelim.MarshalOptions
+}
+
+func (e *delimitedEncoder) Encode(value any) error {
+	message, ok := value.(proto.Message)
+	if !ok {
+		return errors.New("unable to marshal non proto field")
+	}
+	_, err := e.opts.MarshalTo(e.w, message)
+	return err
+}
+
+func (*delimitedEncoder) Close() error { return nil }
+
+type delimitedDecoder struct {
+	src  io.Reader
+	r    *bufio.Reader
+	opts protodelim.UnmarshalOptions
+	// err is the last read error of src except io.EOF.
+	err error
+}
+
+// read reads from src and keeps the read error, so it is not reported as a decode failure.
+func (d *delimitedDecoder) read(p []byte) (int, error) {
+	n, err := d.src.Read(p)
+	if err != nil && err != io.EOF {
+		d.err = err
+	}
+	return n, err
+}
+
+func (d *delimitedDecoder) Decode(value any) error {
+	message, ok := value.(proto.Message)
+	if !ok {
+		return errors.New("unable to unmarshal non proto field")
+	}
+	err := d.opts.UnmarshalFrom(d.r, message)
+	if err == nil || err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	if d.err != nil && errors.Is(err, d.err) {
+		return err
+	}
+	var sizeErr *protodelim.SizeTooLargeError
+	if errors.As(err, &sizeErr) {
+		return fmt.Errorf("%w: %d bytes exceeds %d bytes", ErrMessageTooLarge, sizeErr.Size, sizeErr.MaxSize)
+	}
+	return &codec.FieldError{Type: string(message.ProtoReflect().Descriptor().FullName()), Err: err}
+}
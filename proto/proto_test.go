@@ -1,12 +1,20 @@
 package proto
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"testing"
+	"testing/iotest"
 
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/things-go/encoding/codec"
 	"github.com/things-go/encoding/testdata/examplepb"
 )
 
@@ -124,3 +132,219 @@ func TestCodec_EncoderDecoder(t *testing.T) {
 		t.Fatalf("Decode should returned an error")
 	}
 }
+
+func TestCodec_DecodeFieldError(t *testing.T) {
+	m := Codec{}
+	var fe *codec.FieldError
+	err := m.Unmarshal([]byte{0xff, 0xff}, &examplepb.SimpleMessage{})
+	if !errors.As(err, &fe) {
+		t.Fatalf("error = %v, want *codec.FieldError", err)
+	}
+	if want := string((&examplepb.SimpleMessage{}).ProtoReflect().Descriptor().FullName()); fe.Type != want {
+		t.Errorf("FieldError.Type = %q, want %q", fe.Type, want)
+	}
+}
+
+func TestCodec_Delimited(t *testing.T) {
+	m := &Codec{Delimited: true}
+	if got, want := m.ContentType(nil), "application/x-protobuf; delimited=true"; got != want {
+		t.Errorf("m.ContentType(_) = %q; want %q", got, want)
+	}
+
+	messages := []*examplepb.SimpleMessage{{Id: "1"}, {Id: "2"}, {}}
+	var buf bytes.Buffer
+	encoder := m.NewEncoder(&buf)
+	for _, msg := range messages {
+		if err := encoder.Encode(msg); err != nil {
+			t.Fatalf("Encode returned error: %v", err)
+		}
+	}
+
+	// compatible with protodelim
+	r := bufio.NewReader(bytes.NewReader(buf.Bytes()))
+	for i, want := range messages {
+		got := &examplepb.SimpleMessage{}
+		if err := protodelim.UnmarshalFrom(r, got); err != nil {
+			t.Fatalf("protodelim.UnmarshalFrom #%d returned error: %v", i, err)
+		}
+		if !proto.Equal(got, want) {
+			t.Errorf("protodelim.UnmarshalFrom #%d = %v; want %v", i, got, want)
+		}
+	}
+
+	decoder := m.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for i, want := range messages {
+		got := &examplepb.SimpleMessage{}
+		if err := decoder.Decode(got); err != nil {
+			t.Fatalf("Decode #%d returned error: %v", i, err)
+		}
+		if !proto.Equal(got, want) {
+			t.Errorf("Decode #%d = %v; want %v", i, got, want)
+		}
+	}
+	if err := decoder.Decode(&examplepb.SimpleMessage{}); err != io.EOF {
+		t.Errorf("Decode at the end = %v; want io.EOF", err)
+	}
+
+	truncated := m.NewDecoder(bytes.NewReader(buf.Bytes()[:2]))
+	if err := truncated.Decode(&examplepb.SimpleMessage{}); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Decode truncated = %v; want io.ErrUnexpectedEOF", err)
+	}
+
+	invalid := m.NewDecoder(bytes.NewReader([]byte{0x02, 0xff, 0xff}))
+	var fe *codec.FieldError
+	if err := invalid.Decode(&examplepb.SimpleMessage{}); !errors.As(err, &fe) {
+		t.Errorf("Decode invalid = %v; want *codec.FieldError", err)
+	}
+
+	errBroken := errors.New("broken")
+	broken := m.NewDecoder(io.MultiReader(bytes.NewReader([]byte{0x08}), iotest.ErrReader(errBroken)))
+	if err := broken.Decode(&examplepb.SimpleMessage{}); !errors.Is(err, errBroken) || errors.As(err, &fe) {
+		t.Errorf("Decode broken = %v; want %v", err, errBroken)
+	}
+}
+
+func TestCodec_MaxMessageSize(t *testing.T) {
+	msg := &examplepb.SimpleMessage{Id: "0123456789"}
+	tests := []struct {
+		name  string
+		codec *Codec
+	}{
+		{"whole", &Codec{MaxMessageSize: 8}},
+		{"delimited", &Codec{Delimited: true, MaxMessageSize: 8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.codec.NewEncoder(&buf).Encode(msg); err != nil {
+				t.Fatalf("Encode returned error: %v", err)
+			}
+			err := tt.codec.NewDecoder(&buf).Decode(&examplepb.SimpleMessage{})
+			if !errors.Is(err, ErrMessageTooLarge) {
+				t.Errorf("Decode = %v; want ErrMessageTooLarge", err)
+			}
+
+			buf.Reset()
+			small := &examplepb.SimpleMessage{Id: "1"}
+			if err := tt.codec.NewEncoder(&buf).Encode(small); err != nil {
+				t.Fatalf("Encode returned error: %v", err)
+			}
+			got := &examplepb.SimpleMessage{}
+			if err := tt.codec.NewDecoder(&buf).Decode(got); err != nil {
+				t.Fatalf("Decode returned error: %v", err)
+			}
+			if !proto.Equal(got, small) {
+				t.Errorf("Decode = %v; want %v", got, small)
+			}
+		})
+	}
+}
+
+func TestCodec_WithParams(t *testing.T) {
+	m := &Codec{MaxMessageSize: 10}
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   bool
+	}{
+		{"delimited", map[string]string{"delimited": "true"}, true},
+		{"not delimited", map[string]string{"delimited": "false"}, false},
+		{"invalid", map[string]string{"delimited": "yes"}, false},
+		{"unknown", map[string]string{"charset": "utf-8"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.WithParams(tt.params).(*Codec)
+			if got.Delimited != tt.want {
+				t.Errorf("WithParams(%v).Delimited = %v; want %v", tt.params, got.Delimited, tt.want)
+			}
+			if got.MaxMessageSize != m.MaxMessageSize {
+				t.Errorf("WithParams(%v).MaxMessageSize = %d; want %d", tt.params, got.MaxMessageSize, m.MaxMessageSize)
+			}
+		})
+	}
+	if m.Delimited {
+		t.Errorf("WithParams modified the Codec")
+	}
+}
+
+func TestCodec_Options(t *testing.T) {
+	simple, err := proto.Marshal(&examplepb.SimpleMessage{Id: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// field 15 is unknown to SimpleMessage.
+	withUnknown := append(simple, 0x78, 0x01)
+
+	nested, err := proto.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, delimited := range []bool{false, true} {
+		t.Run(fmt.Sprintf("delimited=%v", delimited), func(t *testing.T) {
+			decode := func(m *Codec, data []byte, v proto.Message) error {
+				if delimited {
+					data = protowire.AppendBytes(nil, data)
+				}
+				return m.NewDecoder(bytes.NewReader(data)).Decode(v)
+			}
+
+			got := &examplepb.SimpleMessage{}
+			if err := decode(&Codec{Delimited: delimited}, withUnknown, got); err != nil {
+				t.Fatalf("Decode returned error: %v", err)
+			}
+			if len(got.ProtoReflect().GetUnknown()) == 0 {
+				t.Errorf("Decode discarded the unknown fields")
+			}
+			m := &Codec{Delimited: delimited}
+			m.DiscardUnknown = true
+			got = &examplepb.SimpleMessage{}
+			if err := decode(m, withUnknown, got); err != nil {
+				t.Fatalf("Decode returned error: %v", err)
+			}
+			if unknown := got.ProtoReflect().GetUnknown(); len(unknown) != 0 {
+				t.Errorf("Decode with DiscardUnknown kept the unknown fields %x", unknown)
+			}
+
+			m = &Codec{Delimited: delimited}
+			m.RecursionLimit = 1
+			if err := decode(m, nested, &examplepb.ABitOfEverything{}); err == nil {
+				t.Errorf("Decode with RecursionLimit should returned an error")
+			}
+
+			m = &Codec{Delimited: delimited}
+			m.Deterministic = true
+			want, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := m.NewEncoder(&buf).Encode(message); err != nil {
+				t.Fatalf("Encode returned error: %v", err)
+			}
+			if delimited {
+				want = protowire.AppendBytes(nil, want)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("Encode with Deterministic = %x; want %x", buf.Bytes(), want)
+			}
+		})
+	}
+
+	m := &Codec{}
+	m.Deterministic = true
+	for i := 0; i < 10; i++ {
+		a, err := m.Marshal(message)
+		if err != nil {
+			t.Fatalf("Marshal returned error: %v", err)
+		}
+		b, err := m.Marshal(message)
+		if err != nil {
+			t.Fatalf("Marshal returned error: %v", err)
+		}
+		if !bytes.Equal(a, b) {
+			t.Fatalf("Marshal with Deterministic is not deterministic")
+		}
+	}
+}
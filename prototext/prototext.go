@@ -0,0 +1,353 @@
+package prototext
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/things-go/encoding/codec"
+)
+
+// Codec is a Marshaler which marshals/unmarshals into/from the protobuf text format
+// with the "google.golang.org/protobuf/encoding/prototext" marshaler,
+// e.g. for debugging and golden files.
+// The output is unstable by design of prototext, do not rely on the exact bytes.
+//
+// Besides the proto messages, it supports the non-message fields like jsonpb.Codec does:
+// a slice (or array) of messages is written as the repeated "item" field,
+// a map of messages is written as the repeated "entry" field of the map entries.
+//
+//	item: {id: "1"}
+//	item: {id: "2"}
+//	entry: {key: "a" value: {id: "1"}}
+//
+// The decode failures are reported as *codec.FieldError.
+type Codec struct {
+	prototext.MarshalOptions
+	prototext.UnmarshalOptions
+}
+
+// ContentType always Returns "text/x-protobuf; charset=utf-8".
+func (*Codec) ContentType(_ any) string {
+	return "text/x-protobuf; charset=utf-8"
+}
+
+func (c *Codec) Marshal(v any) ([]byte, error) {
+	if p, ok := v.(proto.Message); ok {
+		return c.MarshalOptions.Marshal(p)
+	}
+	return c.marshalNonProtoField(v)
+}
+
+// marshalNonProtoField marshals a slice or a map of messages
+// as the repeated field of a synthetic message, see Codec.
+func (c *Codec) marshalNonProtoField(v any) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		desc, err := containerOf(rv.Type().Elem(), false)
+		if err != nil {
+			return nil, err
+		}
+		msg := dynamicpb.NewMessage(desc)
+		list := msg.Mutable(desc.Fields().ByNumber(1)).List()
+		for i := 0; i < rv.Len(); i++ {
+			list.Append(protoreflect.ValueOfMessage(messageOf(rv.Index(i)).ProtoReflect()))
+		}
+		return c.MarshalOptions.Marshal(msg)
+	case reflect.Map:
+		if _, ok := convFromType[rv.Type().Key().Kind()]; !ok {
+			return nil, fmt.Errorf("unsupported type of map field key: %v", rv.Type().Key())
+		}
+		desc, err := containerOf(rv.Type().Elem(), true)
+		if err != nil {
+			return nil, err
+		}
+		msg := dynamicpb.NewMessage(desc)
+		m := msg.Mutable(desc.Fields().ByNumber(1)).Map()
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := protoreflect.ValueOfString(fmt.Sprintf("%v", iter.Key().Interface())).MapKey()
+			m.Set(key, protoreflect.ValueOfMessage(messageOf(iter.Value()).ProtoReflect()))
+		}
+		return c.MarshalOptions.Marshal(msg)
+	default:
+		return nil, errors.New("unable to marshal non proto field")
+	}
+}
+
+// messageOf returns the message of the element, a new empty message if it is nil,
+// an element which is not addressable (e.g. a map value) is copied.
+func messageOf(rv reflect.Value) proto.Message {
+	if rv.Kind() != reflect.Ptr {
+		if !rv.CanAddr() {
+			p := reflect.New(rv.Type())
+			p.Elem().Set(rv)
+			return p.Interface().(proto.Message)
+		}
+		rv = rv.Addr()
+	}
+	if rv.IsNil() {
+		rv = reflect.New(rv.Type().Elem())
+	}
+	return rv.Interface().(proto.Message)
+}
+
+// Unmarshal unmarshals the text format "data" into "v".
+func (c *Codec) Unmarshal(data []byte, v any) error {
+	if p, ok := v.(proto.Message); ok {
+		return wrapError(c.UnmarshalOptions.Unmarshal(data, p), p)
+	}
+	return c.unmarshalNonProtoField(data, v)
+}
+
+// unmarshalNonProtoField unmarshals a slice or a map of messages, see Codec.
+func (c *Codec) unmarshalNonProtoField(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return fmt.Errorf("%T is not a pointer", v)
+	}
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		if rv.Type().Implements(typeProtoMessage) {
+			p := rv.Interface().(proto.Message)
+			return wrapError(c.UnmarshalOptions.Unmarshal(data, p), p)
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Slice:
+		desc, err := containerOf(rv.Type().Elem(), false)
+		if err != nil {
+			return err
+		}
+		msg := dynamicpb.NewMessage(desc)
+		if err = c.UnmarshalOptions.Unmarshal(data, msg); err != nil {
+			return wrapError(err, msg)
+		}
+		list := msg.Get(desc.Fields().ByNumber(1)).List()
+		sl := reflect.MakeSlice(rv.Type(), 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			item, err := c.convert(list.Get(i).Message(), rv.Type().Elem())
+			if err != nil {
+				return err
+			}
+			sl = reflect.Append(sl, item)
+		}
+		rv.Set(sl)
+		return nil
+	case reflect.Map:
+		conv, ok := convFromType[rv.Type().Key().Kind()]
+		if !ok {
+			return fmt.Errorf("unsupported type of map field key: %v", rv.Type().Key())
+		}
+		desc, err := containerOf(rv.Type().Elem(), true)
+		if err != nil {
+			return err
+		}
+		msg := dynamicpb.NewMessage(desc)
+		if err = c.UnmarshalOptions.Unmarshal(data, msg); err != nil {
+			return wrapError(err, msg)
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		msg.Get(desc.Fields().ByNumber(1)).Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			result := conv.Call([]reflect.Value{reflect.ValueOf(k.String())})
+			if e := result[1].Interface(); e != nil {
+				err = &codec.FieldError{Field: k.String(), Value: k.String(), Type: rv.Type().Key().String(), Err: e.(error)}
+				return false
+			}
+			var item reflect.Value
+			if item, err = c.convert(v.Message(), rv.Type().Elem()); err != nil {
+				return false
+			}
+			rv.SetMapIndex(result[0].Convert(rv.Type().Key()), item)
+			return true
+		})
+		return err
+	default:
+		return errors.New("unable to unmarshal non proto field")
+	}
+}
+
+// convert converts the message decoded into the synthetic message to the element type t,
+// the message may be a dynamic message if the element type is not registered.
+func (c *Codec) convert(m protoreflect.Message, t reflect.Type) (reflect.Value, error) {
+	elem := t
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	rv := reflect.New(elem)
+	p := rv.Interface().(proto.Message)
+	if m.Interface() != nil && reflect.TypeOf(m.Interface()) == rv.Type() {
+		p = m.Interface()
+		rv = reflect.ValueOf(p)
+	} else {
+		b, err := proto.MarshalOptions{AllowPartial: true}.Marshal(m.Interface())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if err = (proto.UnmarshalOptions{AllowPartial: true, Resolver: c.UnmarshalOptions.Resolver}).Unmarshal(b, p); err != nil {
+			return reflect.Value{}, wrapError(err, p)
+		}
+	}
+	if t.Kind() != reflect.Ptr {
+		return rv.Elem(), nil
+	}
+	return rv, nil
+}
+
+// NewDecoder returns a Decoder which reads the whole text format from "r".
+func (c *Codec) NewDecoder(r io.Reader) codec.Decoder {
+	return codec.DecoderFunc(func(v any) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return c.Unmarshal(data, v)
+	})
+}
+
+// NewEncoder returns an Encoder which writes the text format into "w".
+// Note that the concatenated messages in the text format are merged when decoding.
+func (c *Codec) NewEncoder(w io.Writer) codec.Encoder {
+	return codec.EncoderFunc(func(v any) error {
+		data, err := c.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	})
+}
+
+// containers caches the synthetic container message descriptors
+// by the element message full name and the kind (list or map).
+var containers sync.Map
+
+type containerKey struct {
+	name  protoreflect.FullName
+	isMap bool
+}
+
+// containerOf returns the descriptor of the synthetic message with the repeated "item" field
+// of the message type t, or the map "entry" field with string keys if isMap.
+func containerOf(t reflect.Type, isMap bool) (protoreflect.MessageDescriptor, error) {
+	if t.Kind() != reflect.Ptr {
+		t = reflect.PointerTo(t)
+	}
+	if !t.Implements(typeProtoMessage) {
+		return nil, errors.New("unable to marshal non proto field")
+	}
+	md := reflect.Zero(t).Interface().(proto.Message).ProtoReflect().Descriptor()
+	key := containerKey{name: md.FullName(), isMap: isMap}
+	if desc, ok := containers.Load(key); ok {
+		return desc.(protoreflect.MessageDescriptor), nil
+	}
+
+	file := md.ParentFile()
+	files := new(protoregistry.Files)
+	if err := files.RegisterFile(file); err != nil {
+		return nil, err
+	}
+	msg := &descriptorpb.DescriptorProto{Name: proto.String("List")}
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String("item"),
+		JsonName: proto.String("item"),
+		Number:   proto.Int32(1),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+		Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+		TypeName: proto.String("." + string(md.FullName())),
+	}
+	if isMap {
+		msg.Name = proto.String("Map")
+		msg.NestedType = []*descriptorpb.DescriptorProto{{
+			Name: proto.String("EntryEntry"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{
+					Name:     proto.String("key"),
+					JsonName: proto.String("key"),
+					Number:   proto.Int32(1),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				},
+				{
+					Name:     proto.String("value"),
+					JsonName: proto.String("value"),
+					Number:   proto.Int32(2),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+					TypeName: proto.String("." + string(md.FullName())),
+				},
+			},
+			Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+		}}
+		field.Name, field.JsonName = proto.String("entry"), proto.String("entry")
+		field.TypeName = proto.String(".things_go.encoding.prototext.Map.EntryEntry")
+	}
+	msg.Field = []*descriptorpb.FieldDescriptorProto{field}
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("things_go/encoding/prototext/" + string(md.FullName()) + ".proto"),
+		Package:     proto.String("things_go.encoding.prototext"),
+		Dependency:  []string{file.Path()},
+		MessageType: []*descriptorpb.DescriptorProto{msg},
+		Syntax:      proto.String("proto3"),
+	}, files)
+	if err != nil {
+		return nil, err
+	}
+	desc, _ := containers.LoadOrStore(key, fd.Messages().Get(0))
+	return desc.(protoreflect.MessageDescriptor), nil
+}
+
+var typeProtoMessage = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+var convFromType = map[reflect.Kind]reflect.Value{
+	reflect.String:  reflect.ValueOf(codec.String),
+	reflect.Bool:    reflect.ValueOf(codec.Bool),
+	reflect.Float64: reflect.ValueOf(codec.Float64),
+	reflect.Float32: reflect.ValueOf(codec.Float32),
+	reflect.Int64:   reflect.ValueOf(codec.Int64),
+	reflect.Int32:   reflect.ValueOf(codec.Int32),
+	reflect.Uint64:  reflect.ValueOf(codec.Uint64),
+	reflect.Uint32:  reflect.ValueOf(codec.Uint32),
+}
+
+// prototext reports an unknown field error like `proto: (line 1:1): unknown field: foo`.
+var protoUnknownFieldRegexp = regexp.MustCompile(`unknown field: (\S+)$`)
+
+// wrapError converts the decode failure of the message into *codec.FieldError.
+func wrapError(err error, p proto.Message) error {
+	if err == nil {
+		return nil
+	}
+	var fe *codec.FieldError
+	if errors.As(err, &fe) {
+		return err
+	}
+	fe = &codec.FieldError{Type: string(p.ProtoReflect().Descriptor().FullName()), Err: err}
+	if m := protoUnknownFieldRegexp.FindStringSubmatch(err.Error()); m != nil {
+		fe.Field = m[1]
+	}
+	return fe
+}
@@ -0,0 +1,206 @@
+package prototext
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/things-go/encoding/codec"
+	"github.com/things-go/encoding/testdata/examplepb"
+)
+
+var message = &examplepb.ABitOfEverything{
+	SingleNested:   &examplepb.ABitOfEverything_Nested{Name: "nested"},
+	TimestampValue: &timestamppb.Timestamp{Seconds: 1},
+	Uuid:           "6EC2446F-7E89-4127-B3E6-5C05E6BECBA7",
+	Nested: []*examplepb.ABitOfEverything_Nested{
+		{
+			Name:   "foo",
+			Amount: 12345,
+		},
+	},
+	Uint64Value: 0xFFFFFFFFFFFFFFFF,
+	EnumValue:   examplepb.NumericEnum_ONE,
+	OneofValue: &examplepb.ABitOfEverything_OneofString{
+		OneofString: "bar",
+	},
+	MapValue: map[string]examplepb.NumericEnum{
+		"a": examplepb.NumericEnum_ONE,
+		"b": examplepb.NumericEnum_ZERO,
+	},
+}
+
+func TestCodec_ContentType(t *testing.T) {
+	var m Codec
+
+	want := "text/x-protobuf; charset=utf-8"
+	if got := m.ContentType(struct{}{}); got != want {
+		t.Errorf("m.ContentType(_) failed, got = %q; want %q; ", got, want)
+	}
+}
+
+func TestCodec_MarshalUnmarshal(t *testing.T) {
+	m := &Codec{}
+	m.Multiline = true
+	m.Indent = "  "
+
+	buf, err := m.Marshal(message)
+	if err != nil {
+		t.Fatalf("m.Marshal(%v) failed with %v; want success", message, err)
+	}
+	if !bytes.Contains(buf, []byte("\n  name:")) {
+		t.Errorf("m.Marshal(%v) = %q; want multiline with indent", message, buf)
+	}
+	got := &examplepb.ABitOfEverything{}
+	if err = m.Unmarshal(buf, got); err != nil {
+		t.Fatalf("m.Unmarshal(%q) failed with %v; want success", buf, err)
+	}
+	if diff := cmp.Diff(got, message, protocmp.Transform()); diff != "" {
+		t.Errorf("m.Unmarshal(%q) differs:\n%s", buf, diff)
+	}
+}
+
+func TestCodec_Any(t *testing.T) {
+	want, err := anypb.New(&examplepb.SimpleMessage{Id: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Codec{}
+	buf, err := m.Marshal(want)
+	if err != nil {
+		t.Fatalf("m.Marshal(%v) failed with %v; want success", want, err)
+	}
+	if !bytes.Contains(buf, []byte("["+want.GetTypeUrl()+"]")) {
+		t.Errorf("m.Marshal(%v) = %q; want the expanded Any", want, buf)
+	}
+	got := &anypb.Any{}
+	if err = m.Unmarshal(buf, got); err != nil {
+		t.Fatalf("m.Unmarshal(%q) failed with %v; want success", buf, err)
+	}
+	if diff := cmp.Diff(got, want, protocmp.Transform()); diff != "" {
+		t.Errorf("m.Unmarshal(%q) differs:\n%s", buf, diff)
+	}
+}
+
+func TestCodec_NonProtoFields(t *testing.T) {
+	m := &Codec{}
+	items := []*examplepb.SimpleMessage{{Id: "1"}, {Id: "2"}}
+
+	t.Run("slice", func(t *testing.T) {
+		buf, err := m.Marshal(items)
+		if err != nil {
+			t.Fatalf("m.Marshal(%v) failed with %v; want success", items, err)
+		}
+		var got []*examplepb.SimpleMessage
+		if err = m.Unmarshal(buf, &got); err != nil {
+			t.Fatalf("m.Unmarshal(%q) failed with %v; want success", buf, err)
+		}
+		if diff := cmp.Diff(got, items, protocmp.Transform()); diff != "" {
+			t.Errorf("m.Unmarshal(%q) differs:\n%s", buf, diff)
+		}
+	})
+	t.Run("text", func(t *testing.T) {
+		var got []*examplepb.SimpleMessage
+		if err := m.Unmarshal([]byte(`item: {id: "1"} item {id: "2"}`), &got); err != nil {
+			t.Fatalf("m.Unmarshal failed with %v; want success", err)
+		}
+		if diff := cmp.Diff(got, items, protocmp.Transform()); diff != "" {
+			t.Errorf("m.Unmarshal differs:\n%s", diff)
+		}
+	})
+	t.Run("map", func(t *testing.T) {
+		want := map[int64]*examplepb.SimpleMessage{1: items[0], 2: items[1]}
+		buf, err := m.Marshal(want)
+		if err != nil {
+			t.Fatalf("m.Marshal(%v) failed with %v; want success", want, err)
+		}
+		got := map[int64]*examplepb.SimpleMessage{}
+		if err = m.Unmarshal(buf, &got); err != nil {
+			t.Fatalf("m.Unmarshal(%q) failed with %v; want success", buf, err)
+		}
+		if diff := cmp.Diff(got, want, protocmp.Transform()); diff != "" {
+			t.Errorf("m.Unmarshal(%q) differs:\n%s", buf, diff)
+		}
+	})
+	t.Run("map of values", func(t *testing.T) {
+		want := map[string]examplepb.SimpleMessage{"a": {Id: "1"}}
+		buf, err := m.Marshal(want)
+		if err != nil {
+			t.Fatalf("m.Marshal(%v) failed with %v; want success", want, err)
+		}
+		got := map[string]*examplepb.SimpleMessage{}
+		if err = m.Unmarshal(buf, &got); err != nil {
+			t.Fatalf("m.Unmarshal(%q) failed with %v; want success", buf, err)
+		}
+		if got["a"].GetId() != "1" {
+			t.Errorf("m.Unmarshal(%q) = %v; want the values", buf, got)
+		}
+	})
+	t.Run("array of values", func(t *testing.T) {
+		var want any = [2]examplepb.SimpleMessage{{Id: "1"}, {Id: "2"}}
+		buf, err := m.Marshal(want)
+		if err != nil {
+			t.Fatalf("m.Marshal(%v) failed with %v; want success", want, err)
+		}
+		var got []*examplepb.SimpleMessage
+		if err = m.Unmarshal(buf, &got); err != nil {
+			t.Fatalf("m.Unmarshal(%q) failed with %v; want success", buf, err)
+		}
+		if diff := cmp.Diff(got, items, protocmp.Transform()); diff != "" {
+			t.Errorf("m.Unmarshal(%q) differs:\n%s", buf, diff)
+		}
+	})
+	t.Run("invalid map key", func(t *testing.T) {
+		got := map[int64]*examplepb.SimpleMessage{}
+		var fe *codec.FieldError
+		err := m.Unmarshal([]byte(`entry: {key: "a" value: {id: "1"}}`), &got)
+		if !errors.As(err, &fe) || fe.Field != "a" {
+			t.Errorf("m.Unmarshal() = %v; want *codec.FieldError of the key", err)
+		}
+	})
+	t.Run("non proto", func(t *testing.T) {
+		if _, err := m.Marshal([]string{"a"}); err == nil {
+			t.Errorf("m.Marshal([]string) should returned an error")
+		}
+		var got []string
+		if err := m.Unmarshal([]byte(`item: "a"`), &got); err == nil {
+			t.Errorf("m.Unmarshal([]string) should returned an error")
+		}
+	})
+}
+
+func TestCodec_EncoderDecoder(t *testing.T) {
+	m := &Codec{}
+	var buf bytes.Buffer
+	if err := m.NewEncoder(&buf).Encode(message); err != nil {
+		t.Fatalf("Encode(%v) failed with %v; want success", message, err)
+	}
+	got := &examplepb.ABitOfEverything{}
+	if err := m.NewDecoder(&buf).Decode(got); err != nil {
+		t.Fatalf("Decode() failed with %v; want success", err)
+	}
+	if !proto.Equal(got, message) {
+		t.Errorf("Decode() = %v; want %v", got, message)
+	}
+}
+
+func TestCodec_DecodeFieldError(t *testing.T) {
+	m := &Codec{}
+	var fe *codec.FieldError
+	err := m.Unmarshal([]byte(`foo: 1`), &examplepb.SimpleMessage{})
+	if !errors.As(err, &fe) {
+		t.Fatalf("error = %v, want *codec.FieldError", err)
+	}
+	if fe.Field != "foo" {
+		t.Errorf("FieldError.Field = %q, want %q", fe.Field, "foo")
+	}
+	if want := string((&examplepb.SimpleMessage{}).ProtoReflect().Descriptor().FullName()); fe.Type != want {
+		t.Errorf("FieldError.Type = %q, want %q", fe.Type, want)
+	}
+}
@@ -0,0 +1,287 @@
+package encoding
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/things-go/encoding/codec"
+)
+
+// registry is an immutable snapshot of the Encoding's MIME type marshalers and settings.
+// It is never modified after published, the Encoding replaces it with an updated copy,
+// so it is safe to read without locking.
+type registry struct {
+	mimeMap      map[string]codec.Marshaler
+	mimeQuery    codec.FormMarshaler
+	mimeUri      codec.UriMarshaler
+	mimeWildcard codec.Marshaler
+	strict       bool
+	mergeQuery   bool
+	limits       Limits
+	mimeLimits   map[string]Limits
+	// compressors is the content coding compressors,
+	// compressorOrder is the content codings in registration order.
+	compressors     map[string]Compressor
+	compressorOrder []string
+	compressMinSize int
+	// problemMappers is the ProblemMappers in registration order.
+	problemMappers []ProblemMapper
+}
+
+// clone returns a copy of the registry which can be modified before published.
+func (r *registry) clone() *registry {
+	c := *r
+	c.mimeMap = make(map[string]codec.Marshaler, len(r.mimeMap))
+	for k, v := range r.mimeMap {
+		c.mimeMap[k] = v
+	}
+	c.mimeLimits = make(map[string]Limits, len(r.mimeLimits))
+	for k, v := range r.mimeLimits {
+		c.mimeLimits[k] = v
+	}
+	c.compressors = make(map[string]Compressor, len(r.compressors))
+	for k, v := range r.compressors {
+		c.compressors[k] = v
+	}
+	c.compressorOrder = append([]string(nil), r.compressorOrder...)
+	c.problemMappers = append([]ProblemMapper(nil), r.problemMappers...)
+	return &c
+}
+
+// registered returns the sorted registered MIME types,
+// the special MIMEWildcard, MIMEQuery and MIMEURI excluded.
+func (r *registry) registered() []string {
+	mimes := make([]string, 0, len(r.mimeMap))
+	for mime := range r.mimeMap {
+		mimes = append(mimes, mime)
+	}
+	sort.Strings(mimes)
+	return mimes
+}
+
+// suffixBaseMIMEs maps the structured syntax suffix to the base MIME types,
+// the media types with the suffix fall back to the first registered one.
+var suffixBaseMIMEs = map[string][]string{
+	SuffixJSON:     {MIMEJSON},
+	SuffixXML:      {MIMEXML, MIMEXML2},
+	SuffixYAML:     {MIMEYAML, MIMEYAML2},
+	SuffixCBOR:     {MIMECBOR},
+	SuffixPROTO:    {MIMEPROTOBUF},
+	SuffixPROTOBUF: {MIMEPROTOBUF},
+}
+
+// get returns the marshalers with a case-sensitive MIME type string,
+// see Encoding.Get.
+func (r *registry) get(mime string) codec.Marshaler {
+	switch mime {
+	case MIMEQuery:
+		return r.mimeQuery
+	case MIMEURI:
+		return r.mimeUri
+	case MIMEWildcard:
+		return r.mimeWildcard
+	default:
+		m := r.lookup(mime)
+		if m == nil {
+			m = r.mimeWildcard
+		}
+		return m
+	}
+}
+
+// lookup returns the marshaler with a case-sensitive MIME type string.
+// It checks the MIME type on the registry, if there is no exact entry and the MIME type
+// has a structured syntax suffix (RFC 6839), e.g. "application/problem+json",
+// it checks the suffix (e.g. "+json") on the registry, then the base MIME types
+// of the suffix (e.g. "application/json").
+// It returns nil if not found.
+func (r *registry) lookup(mime string) codec.Marshaler {
+	if m, ok := r.mimeMap[mime]; ok {
+		return m
+	}
+	suffix := structuredSyntaxSuffix(mime)
+	if suffix == "" {
+		return nil
+	}
+	if m, ok := r.mimeMap[suffix]; ok {
+		return m
+	}
+	for _, base := range suffixBaseMIMEs[suffix] {
+		if m, ok := r.mimeMap[base]; ok {
+			return m
+		}
+	}
+	return nil
+}
+
+// structuredSyntaxSuffix returns the structured syntax suffix of the MIME type with the "+",
+// e.g. "+json" for "application/problem+json", or empty if none.
+func structuredSyntaxSuffix(mime string) string {
+	slash := strings.IndexByte(mime, '/')
+	plus := strings.LastIndexByte(mime, '+')
+	if slash < 0 || plus <= slash+1 || plus == len(mime)-1 {
+		return ""
+	}
+	return mime[plus:]
+}
+
+// limitsFor returns the Limits for the MIME type,
+// the MIME type's Limits if set, otherwise the global Limits.
+func (r *registry) limitsFor(mime string) Limits {
+	if l, ok := r.mimeLimits[mime]; ok {
+		return l
+	}
+	return r.limits
+}
+
+// negotiateInbound returns the inbound `Content-Type` and marshalers for this request,
+// see Encoding.NegotiateInbound.
+func (r *registry) negotiateInbound(req *http.Request) (string, codec.Marshaler, error) {
+	values := req.Header[contentTypeHeader]
+	contentType, marshaler := r.marshalerFromHeaderContentType(values)
+	if marshaler != nil {
+		return contentType, marshaler, nil
+	}
+	if header := strings.Join(values, ", "); r.strict && strings.TrimSpace(header) != "" {
+		return "", nil, r.newMediaTypeError(ErrUnsupportedMediaType, header)
+	}
+	return MIMEWildcard, r.mimeWildcard, nil
+}
+
+// negotiateOutbound returns the negotiated media type and marshalers for this request,
+// see Encoding.NegotiateOutbound.
+func (r *registry) negotiateOutbound(req *http.Request) (string, codec.Marshaler, error) {
+	values := req.Header[acceptHeader]
+	mediaType, marshaler := r.marshalerFromHeaderAccept(values)
+	if marshaler != nil {
+		return mediaType, marshaler, nil
+	}
+	if r.strict {
+		return "", nil, r.newMediaTypeError(ErrNotAcceptable, strings.Join(values, ", "))
+	}
+	return MIMEWildcard, r.mimeWildcard, nil
+}
+
+// marshalerFromHeaderContentType returns the `Content-Type` and marshaler from `Content-Type` header.
+// It checks the registry for the MIME type set by the `Content-Type` header.
+// If there are multiple `Content-Type` headers set, choose the first one that it can
+// match in the registry, see lookup.
+// It returns nil marshaler if no registered MIME type matched.
+func (r *registry) marshalerFromHeaderContentType(values []string) (string, codec.Marshaler) {
+	for _, contentTypeVal := range values {
+		contentType, params, err := mime.ParseMediaType(contentTypeVal)
+		if err != nil {
+			continue
+		}
+		if m := r.lookup(contentType); m != nil {
+			return contentType, withParams(m, params)
+		}
+	}
+	return "", nil
+}
+
+// marshalerFromHeaderAccept returns the negotiated media type and marshalers from `Accept` header.
+// It negotiates with the registry for the media ranges set by the `Accept` header.
+// If it isn't set (or the `Accept` is empty), checks for "*".
+// It returns nil marshaler if no registered MIME type is acceptable.
+func (r *registry) marshalerFromHeaderAccept(values []string) (string, codec.Marshaler) {
+	return r.negotiate(parseAccept(values))
+}
+
+// candidate is a registered MIME type matched by a media range.
+type candidate struct {
+	mediaType string
+	marshaler codec.Marshaler
+	q         float64
+	matched   *mediaRange
+	wildcard  bool
+}
+
+// better reports whether the candidate is more acceptable than other.
+// The "*" Marshaler is preferred by "*/*" and "type/*", the registered one is
+// preferred by the exact media range.
+func (c *candidate) better(other *candidate) bool {
+	if c.q != other.q {
+		return c.q > other.q
+	}
+	if cs, os := c.matched.specificity(), other.matched.specificity(); cs != os {
+		return cs > os
+	}
+	if c.matched.index != other.matched.index {
+		return c.matched.index < other.matched.index
+	}
+	if c.wildcard != other.wildcard {
+		return c.wildcard == (c.matched.specificity() < 2)
+	}
+	return c.mediaType < other.mediaType
+}
+
+// inboundMIMEs are the MIME types of the request bodies, e.g. the forms,
+// which are rendered only if they are accepted exactly, never by "*/*" or "type/*".
+var inboundMIMEs = map[string]bool{
+	MIMEPOSTForm:          true,
+	MIMEMultipartPOSTForm: true,
+}
+
+// negotiate returns the most acceptable MIME type and marshaler in the registry.
+// If there is no media ranges, it returns "*" Marshaler.
+// It returns nil marshaler if no registered MIME type is acceptable.
+func (r *registry) negotiate(ranges []*mediaRange) (string, codec.Marshaler) {
+	if len(ranges) == 0 {
+		return MIMEWildcard, r.mimeWildcard
+	}
+
+	var best *candidate
+
+	try := func(c *candidate) {
+		c.q, c.matched = quality(ranges, c.mediaType)
+		if c.matched == nil || c.q == 0 {
+			return
+		}
+		if inboundMIMEs[c.mediaType] && c.matched.specificity() < 2 {
+			return
+		}
+		if best == nil || c.better(best) {
+			best = c
+		}
+	}
+	if mediaType := baseMediaType(r.mimeWildcard.ContentType(nil)); mediaType != "" {
+		try(&candidate{mediaType: mediaType, marshaler: r.mimeWildcard, wildcard: true})
+	}
+	for mediaType, marshaler := range r.mimeMap {
+		if !strings.HasPrefix(mediaType, "+") {
+			try(&candidate{mediaType: mediaType, marshaler: marshaler})
+		}
+	}
+	// the exact media ranges which are not registered but have a structured syntax suffix.
+	for _, rg := range ranges {
+		if rg.specificity() < 2 {
+			continue
+		}
+		if _, ok := r.mimeMap[rg.mediaType]; ok {
+			continue
+		}
+		if marshaler := r.lookup(rg.mediaType); marshaler != nil {
+			try(&candidate{mediaType: rg.mediaType, marshaler: marshaler})
+		}
+	}
+	if best == nil {
+		return "", nil
+	}
+	marshaler := withParams(best.marshaler, best.matched.params)
+	if best.wildcard {
+		return MIMEWildcard, marshaler
+	}
+	return best.mediaType, marshaler
+}
+
+// withParams returns the marshaler configured by the media type parameters
+// if it is a codec.ParamsMarshaler, otherwise the marshaler itself.
+func withParams(m codec.Marshaler, params map[string]string) codec.Marshaler {
+	if pm, ok := m.(codec.ParamsMarshaler); ok && len(params) > 0 {
+		return pm.WithParams(params)
+	}
+	return m
+}
@@ -0,0 +1,263 @@
+package encoding
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	etagHeader            = http.CanonicalHeaderKey("ETag")
+	lastModifiedHeader    = http.CanonicalHeaderKey("Last-Modified")
+	ifNoneMatchHeader     = http.CanonicalHeaderKey("If-None-Match")
+	ifModifiedSinceHeader = http.CanonicalHeaderKey("If-Modified-Since")
+)
+
+// RenderOptions is the options of RenderWith.
+type RenderOptions struct {
+	// Status is the HTTP status code, default is 200.
+	Status int
+	// Header is the extra response headers, they replace the existing ones with the same key,
+	// and are written only if the response is written.
+	Header http.Header
+	// ETag enables the strong ETag computed from the encoded body,
+	// the whole body is buffered to compute it.
+	ETag bool
+	// Version is the caller-supplied version of v, it is used as the strong ETag
+	// instead of the computed one, and answers `If-None-Match` without encoding v.
+	// A quoted version is used as is, so a weak ETag like `W/"v1"` is kept weak.
+	Version string
+	// LastModified is the modification time of v, it is written as `Last-Modified`
+	// and answers `If-Modified-Since`.
+	LastModified time.Time
+}
+
+// RenderWith is like Render, but writes the response with the RenderOptions.
+//
+// The conditional requests are answered only for the GET and HEAD requests with status 200,
+// `If-None-Match` is compared with the ETag by the weak comparison, `If-Modified-Since` is
+// compared with the LastModified only if `If-None-Match` is absent, a matched request is
+// answered with 304 Not Modified without the body.
+// The ETag of a compressed body is suffixed by the content coding, e.g. "\"v1-gzip\"",
+// so the representations of different content codings have different ETags.
+//
+// The body of a HEAD request is encoded to set the `Content-Length` and ETag, but not written.
+// The status 1xx, 204 and 304 are written without the body.
+// The `Content-Length` is set unless the body is streamed, i.e. a compressed or large
+// body without the ETag. The `Vary: Accept` is always added, as the response is negotiated.
+func (r *Encoding) RenderWith(w http.ResponseWriter, req *http.Request, v any, opts RenderOptions) error {
+	if opts.Status == 0 {
+		if v == nil {
+			return nil
+		}
+		opts.Status = http.StatusOK
+	}
+	header := w.Header()
+	if v == nil || !bodyAllowed(opts.Status) {
+		copyHeader(header, opts.Header)
+		w.WriteHeader(opts.Status)
+		return nil
+	}
+	reg := r.load()
+	mediaType, marshaller, err := reg.negotiateOutbound(req)
+	if err != nil {
+		return err
+	}
+	header.Set(contentTypeHeader, contentTypeFor(mediaType, marshaller, v))
+	addVary(header, acceptHeader)
+
+	coding, compressor := reg.responseCompressor(req, header, opts.Header)
+	if !opts.LastModified.IsZero() {
+		header.Set(lastModifiedHeader, opts.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	conditional := opts.Status == http.StatusOK &&
+		(req.Method == http.MethodGet || req.Method == http.MethodHead)
+	// the validators are known before encoding.
+	if conditional && (opts.Version != "" || (!opts.ETag && !opts.LastModified.IsZero())) {
+		var etags []string
+		if opts.Version != "" {
+			etags = append(etags, quoteETag(opts.Version))
+			if compressor != nil {
+				etags = append(etags, etagWithCoding(etags[0], coding))
+			}
+		}
+		if etag, ok := notModified(req, etags, opts.LastModified); ok {
+			writeNotModified(w, etag, opts.Header)
+			return nil
+		}
+	}
+
+	if opts.Version == "" && !opts.ETag && req.Method != http.MethodHead {
+		buf := newResponseBuffer(w)
+		defer buf.Release()
+		buf.status, buf.header = opts.Status, opts.Header
+		if compressor != nil {
+			buf.coding, buf.compressor, buf.minSize = coding, compressor, reg.compressMinSize
+		}
+		if err = marshaller.NewEncoder(buf).Encode(v); err != nil {
+			if !buf.Committed() {
+				header.Del(contentTypeHeader)
+				header.Del(lastModifiedHeader)
+			}
+			return err
+		}
+		return buf.Close()
+	}
+
+	// the whole body is buffered to compute the ETag or `Content-Length`.
+	body := renderBufferPool.Get().(*bytes.Buffer)
+	defer putRenderBuffer(body)
+	if err = marshaller.NewEncoder(body).Encode(v); err != nil {
+		header.Del(contentTypeHeader)
+		header.Del(lastModifiedHeader)
+		return err
+	}
+	data := body.Bytes()
+	var etag string
+	if opts.Version != "" {
+		etag = quoteETag(opts.Version)
+	} else if opts.ETag {
+		etag = computeETag(data)
+	}
+	if compressor != nil && len(data) >= reg.compressMinSize {
+		if data, err = compress(compressor, data); err != nil {
+			header.Del(contentTypeHeader)
+			header.Del(lastModifiedHeader)
+			return err
+		}
+		header.Set(contentEncodingHeader, coding)
+		if etag != "" {
+			etag = etagWithCoding(etag, coding)
+		}
+	}
+	if etag != "" {
+		if conditional {
+			if _, ok := notModified(req, []string{etag}, opts.LastModified); ok {
+				header.Del(contentEncodingHeader)
+				writeNotModified(w, etag, opts.Header)
+				return nil
+			}
+		}
+		header.Set(etagHeader, etag)
+	}
+	copyHeader(header, opts.Header)
+	header.Set(contentLengthHeader, strconv.Itoa(len(data)))
+	w.WriteHeader(opts.Status)
+	if req.Method == http.MethodHead {
+		return nil
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// bodyAllowed reports whether a response with the status may have a body.
+func bodyAllowed(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
+// copyHeader sets the headers of src into dst, replacing the existing ones with the same key.
+func copyHeader(dst, src http.Header) {
+	for k, vs := range src {
+		dst.Del(k)
+		for _, v := range vs {
+			dst.Add(k, v)
+		}
+	}
+}
+
+// compress returns the data compressed by the Compressor.
+func compress(c Compressor, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	cw, err := c.NewWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = cw.Write(data); err != nil {
+		discardWriter(cw)
+		return nil, err
+	}
+	if err = cw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// computeETag returns the strong ETag of the data.
+func computeETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// quoteETag returns the version as a strong ETag, the quoted version is returned as is,
+// including a weak ETag.
+func quoteETag(version string) string {
+	if len(version) >= 2 && strings.HasSuffix(version, `"`) &&
+		(strings.HasPrefix(version, `"`) || strings.HasPrefix(version, `W/"`)) {
+		return version
+	}
+	return `"` + version + `"`
+}
+
+// etagWithCoding returns the ETag suffixed by the content coding.
+func etagWithCoding(etag, coding string) string {
+	return etag[:len(etag)-1] + "-" + coding + `"`
+}
+
+// notModified reports whether the conditional request is not modified, and the
+// matched ETag, see RFC 9110 section 13.2.2.
+// `If-None-Match` is compared by the weak comparison with etags, "*" matches any,
+// `If-Modified-Since` is evaluated only if `If-None-Match` is absent.
+func notModified(req *http.Request, etags []string, lastModified time.Time) (string, bool) {
+	if values := req.Header[ifNoneMatchHeader]; len(values) > 0 {
+		for _, value := range values {
+			for _, tag := range strings.Split(value, ",") {
+				tag = strings.TrimSpace(tag)
+				if tag == "*" {
+					if len(etags) > 0 {
+						return etags[0], true
+					}
+					return "", true
+				}
+				for _, etag := range etags {
+					if strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+						return etag, true
+					}
+				}
+			}
+		}
+		return "", false
+	}
+	if lastModified.IsZero() {
+		return "", false
+	}
+	since, err := http.ParseTime(req.Header.Get(ifModifiedSinceHeader))
+	if err != nil {
+		return "", false
+	}
+	return "", !lastModified.Truncate(time.Second).After(since)
+}
+
+// writeNotModified writes the 304 Not Modified response with the etag,
+// the representation headers are removed.
+func writeNotModified(w http.ResponseWriter, etag string, extra http.Header) {
+	header := w.Header()
+	header.Del(contentTypeHeader)
+	header.Del(contentLengthHeader)
+	copyHeader(header, extra)
+	if etag != "" {
+		header.Set(etagHeader, etag)
+		header.Del(lastModifiedHeader)
+	}
+	w.WriteHeader(http.StatusNotModified)
+}
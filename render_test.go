@@ -0,0 +1,176 @@
+package encoding
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Encoding_RenderWith(t *testing.T) {
+	v := &TestMode{Id: "foo", Name: "bar"}
+	body := `{"id":"foo","name":"bar"}` + "\n" // written by json.Encoder, see Render.
+
+	t.Run("status and headers", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "http://example.com", nil)
+		w := httptest.NewRecorder()
+		w.Header().Set("Cache-Control", "no-cache")
+		err := New().RenderWith(w, r, v, RenderOptions{
+			Status: http.StatusCreated,
+			Header: http.Header{"Location": {"/foo"}, "Cache-Control": {"max-age=60"}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.Equal(t, "/foo", w.Header().Get("Location"))
+		require.Equal(t, []string{"max-age=60"}, w.Header().Values("Cache-Control"))
+		require.Equal(t, strconv.Itoa(len(body)), w.Header().Get("Content-Length"))
+		require.Equal(t, "Accept", w.Header().Get("Vary"))
+		require.Empty(t, w.Header().Get("ETag"))
+		require.Equal(t, body, w.Body.String())
+	})
+	t.Run("encode failed", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		w := httptest.NewRecorder()
+		err := New().RenderWith(w, r, make(chan int), RenderOptions{
+			Header: http.Header{"Location": {"/foo"}},
+		})
+		require.Error(t, err)
+		require.Empty(t, w.Header().Get("Location"))
+		require.Empty(t, w.Header().Get("Content-Type"))
+	})
+	t.Run("no content", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodDelete, "http://example.com", nil)
+		w := httptest.NewRecorder()
+		require.NoError(t, New().RenderWith(w, r, nil, RenderOptions{Status: http.StatusNoContent}))
+		require.Equal(t, http.StatusNoContent, w.Code)
+		require.Empty(t, w.Header().Get("Content-Type"))
+		require.Zero(t, w.Body.Len())
+	})
+	t.Run("head", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodHead, "http://example.com", nil)
+		w := httptest.NewRecorder()
+		require.NoError(t, New().RenderWith(w, r, v, RenderOptions{ETag: true}))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, strconv.Itoa(len(body)), w.Header().Get("Content-Length"))
+		require.Equal(t, computeETag([]byte(body)), w.Header().Get("ETag"))
+		require.Zero(t, w.Body.Len())
+	})
+	t.Run("etag", func(t *testing.T) {
+		etag := computeETag([]byte(body))
+		tests := []struct {
+			name        string
+			method      string
+			ifNoneMatch string
+			wantStatus  int
+		}{
+			{"no condition", http.MethodGet, "", http.StatusOK},
+			{"match", http.MethodGet, etag, http.StatusNotModified},
+			{"weak match", http.MethodGet, `"other", W/` + etag, http.StatusNotModified},
+			{"any", http.MethodGet, "*", http.StatusNotModified},
+			{"head match", http.MethodHead, etag, http.StatusNotModified},
+			{"mismatch", http.MethodGet, `"other"`, http.StatusOK},
+			{"unsafe method", http.MethodPost, etag, http.StatusOK},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				r := httptest.NewRequest(tt.method, "http://example.com", nil)
+				if tt.ifNoneMatch != "" {
+					r.Header.Set("If-None-Match", tt.ifNoneMatch)
+				}
+				w := httptest.NewRecorder()
+				require.NoError(t, New().RenderWith(w, r, v, RenderOptions{ETag: true}))
+				require.Equal(t, tt.wantStatus, w.Code)
+				require.Equal(t, etag, w.Header().Get("ETag"))
+				if tt.wantStatus == http.StatusNotModified {
+					require.Empty(t, w.Header().Get("Content-Type"))
+					require.Empty(t, w.Header().Get("Content-Length"))
+					require.Zero(t, w.Body.Len())
+				} else if tt.method != http.MethodHead {
+					require.Equal(t, body, w.Body.String())
+				}
+			})
+		}
+	})
+	t.Run("version", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		r.Header.Set("If-None-Match", `"v1"`)
+		w := httptest.NewRecorder()
+		// not encoded if not modified.
+		require.NoError(t, New().RenderWith(w, r, make(chan int), RenderOptions{Version: "v1"}))
+		require.Equal(t, http.StatusNotModified, w.Code)
+		require.Equal(t, `"v1"`, w.Header().Get("ETag"))
+
+		r.Header.Set("If-None-Match", `"v0"`)
+		w = httptest.NewRecorder()
+		require.NoError(t, New().RenderWith(w, r, v, RenderOptions{Version: "v1", ETag: true}))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, `"v1"`, w.Header().Get("ETag"))
+		require.Equal(t, body, w.Body.String())
+
+		r.Header.Set("If-None-Match", `"v1"`)
+		w = httptest.NewRecorder()
+		require.NoError(t, New().RenderWith(w, r, make(chan int), RenderOptions{Version: `W/"v1"`}))
+		require.Equal(t, http.StatusNotModified, w.Code)
+		require.Equal(t, `W/"v1"`, w.Header().Get("ETag"), "a weak version is kept weak")
+	})
+	t.Run("large buffer", func(t *testing.T) {
+		buf := bytes.NewBuffer(make([]byte, 0, maxRenderBufferSize+1))
+		putRenderBuffer(buf)
+		for i := 0; i < 8; i++ {
+			require.LessOrEqual(t, renderBufferPool.Get().(*bytes.Buffer).Cap(), maxRenderBufferSize)
+		}
+	})
+	t.Run("compressed etag", func(t *testing.T) {
+		large := &TestMode{Id: "foo", Name: strings.Repeat("bar", 1024)}
+		r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		require.NoError(t, New().RenderWith(w, r, large, RenderOptions{Version: "v1"}))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, EncodingGzip, w.Header().Get("Content-Encoding"))
+		require.Equal(t, `"v1-gzip"`, w.Header().Get("ETag"))
+		require.Equal(t, strconv.Itoa(w.Body.Len()), w.Header().Get("Content-Length"))
+
+		r.Header.Set("If-None-Match", `"v1-gzip"`)
+		w = httptest.NewRecorder()
+		require.NoError(t, New().RenderWith(w, r, large, RenderOptions{Version: "v1"}))
+		require.Equal(t, http.StatusNotModified, w.Code)
+		require.Equal(t, `"v1-gzip"`, w.Header().Get("ETag"))
+		require.Empty(t, w.Header().Get("Content-Encoding"))
+	})
+	t.Run("last modified", func(t *testing.T) {
+		modified := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+		tests := []struct {
+			name            string
+			ifModifiedSince string
+			ifNoneMatch     string
+			wantStatus      int
+		}{
+			{"no condition", "", "", http.StatusOK},
+			{"not modified", modified.Format(http.TimeFormat), "", http.StatusNotModified},
+			{"modified", modified.Add(-time.Second).Format(http.TimeFormat), "", http.StatusOK},
+			{"invalid", "yesterday", "", http.StatusOK},
+			{"if-none-match precedence", modified.Format(http.TimeFormat), `"other"`, http.StatusOK},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+				if tt.ifModifiedSince != "" {
+					r.Header.Set("If-Modified-Since", tt.ifModifiedSince)
+				}
+				if tt.ifNoneMatch != "" {
+					r.Header.Set("If-None-Match", tt.ifNoneMatch)
+				}
+				w := httptest.NewRecorder()
+				require.NoError(t, New().RenderWith(w, r, v, RenderOptions{LastModified: modified}))
+				require.Equal(t, tt.wantStatus, w.Code)
+				require.Equal(t, modified.Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+			})
+		}
+	})
+}
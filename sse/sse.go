@@ -0,0 +1,280 @@
+// Package sse implements the Server-Sent Events (text/event-stream) writer and reader,
+// the event data is encoded and decoded by a codec.Marshaler.
+// see https://html.spec.whatwg.org/multipage/server-sent-events.html
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/things-go/encoding/codec"
+)
+
+// ContentType is the MIME type of the Server-Sent Events.
+const ContentType = "text/event-stream"
+
+// ErrInvalidField is reported when the event type or id contains a line break,
+// or the id contains a NULL character.
+var ErrInvalidField = errors.New("sse: invalid event field")
+
+// Event is a Server-Sent Event.
+type Event struct {
+	// ID is the event id, it is the last event id when read.
+	ID string
+	// Event is the event type, empty means "message".
+	Event string
+	// Retry is the reconnection time, it is written in milliseconds if positive,
+	// it is the last reconnection time when read.
+	Retry time.Duration
+	// Data is the event data, it is encoded by the codec.Marshaler if not nil.
+	Data any
+}
+
+// Writer writes the Server-Sent Events to a http.ResponseWriter.
+// Each event is flushed after written, and it stops writing when
+// the request context is done.
+type Writer struct {
+	w         http.ResponseWriter
+	rc        *http.ResponseController
+	ctx       context.Context
+	marshaler codec.Marshaler
+}
+
+// NewWriter writes the response headers of the Server-Sent Events,
+// commits the response with status 200, and returns a Writer which
+// encodes the event data with the marshaler.
+func NewWriter(w http.ResponseWriter, req *http.Request, marshaler codec.Marshaler) *Writer {
+	header := w.Header()
+	header.Set("Content-Type", ContentType)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no") // disable the proxy buffering, e.g. nginx.
+	header.Del("Content-Length")
+	w.WriteHeader(http.StatusOK)
+
+	sw := &Writer{
+		w:         w,
+		rc:        http.NewResponseController(w),
+		ctx:       req.Context(),
+		marshaler: marshaler,
+	}
+	_ = sw.flush()
+	return sw
+}
+
+// Encode writes v as the data of an event without type and id,
+// it implements codec.Encoder.
+func (w *Writer) Encode(v any) error {
+	return w.Send(&Event{Data: v})
+}
+
+// Send writes the event and flushes it.
+// The encoded data with line breaks is written as multiple "data" lines.
+// It returns the context error if the request context is done.
+func (w *Writer) Send(e *Event) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	if strings.ContainsAny(e.Event, "\r\n") || strings.ContainsAny(e.ID, "\r\n\x00") {
+		return ErrInvalidField
+	}
+
+	var buf bytes.Buffer
+	if e.Event != "" {
+		writeField(&buf, "event", e.Event)
+	}
+	if e.ID != "" {
+		writeField(&buf, "id", e.ID)
+	}
+	if e.Retry > 0 {
+		writeField(&buf, "retry", strconv.FormatInt(e.Retry.Milliseconds(), 10))
+	}
+	if e.Data != nil {
+		data, err := w.marshaler.Marshal(e.Data)
+		if err != nil {
+			return err
+		}
+		for _, line := range splitLines(string(data)) {
+			writeField(&buf, "data", line)
+		}
+	}
+	buf.WriteByte('\n')
+	return w.write(buf.Bytes())
+}
+
+// Comment writes a comment line, it is ignored by the clients,
+// and usually used as a keep-alive.
+func (w *Writer) Comment(text string) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, line := range splitLines(text) {
+		buf.WriteString(":")
+		if line != "" {
+			buf.WriteString(" ")
+			buf.WriteString(line)
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	return w.write(buf.Bytes())
+}
+
+func (w *Writer) write(p []byte) error {
+	if _, err := w.w.Write(p); err != nil {
+		return err
+	}
+	return w.flush()
+}
+
+func (w *Writer) flush() error {
+	if err := w.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// Stream writes the events from the channel until the channel is closed,
+// or the request context is done, in which case the context error is returned.
+func (w *Writer) Stream(events <-chan *Event) error {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return w.ctx.Err()
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := w.Send(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// splitLines splits s by the line breaks "\r\n", "\n" and "\r".
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.Split(s, "\n")
+}
+
+// Reader reads the Server-Sent Events, and decodes the event data with a codec.Marshaler.
+type Reader struct {
+	r         *bufio.Reader
+	marshaler codec.Marshaler
+	lastID    string
+	retry     time.Duration
+	skipLF    bool // the last line is terminated by "\r".
+}
+
+// NewReader returns a Reader which reads from r, and decodes the event data with the marshaler.
+func NewReader(r io.Reader, marshaler codec.Marshaler) *Reader {
+	return &Reader{
+		r:         bufio.NewReader(r),
+		marshaler: marshaler,
+	}
+}
+
+// Decode reads the next event, and decodes its data into v,
+// it implements codec.Decoder.
+func (r *Reader) Decode(v any) error {
+	_, err := r.ReadEvent(v)
+	return err
+}
+
+// ReadEvent reads the next event, the event data is decoded into v,
+// and the returned Event.Data is v, or the raw []byte data if v is nil.
+// The events without data are not dispatched, but their id and retry take effect.
+// The Event.ID and Event.Retry are the last received ones.
+// It returns io.EOF at the end of the stream, the incomplete last event is discarded.
+func (r *Reader) ReadEvent(v any) (*Event, error) {
+	var (
+		data    bytes.Buffer
+		hasData bool
+		e       Event
+	)
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 {
+			if !hasData {
+				e = Event{}
+				continue
+			}
+			e.ID, e.Retry = r.lastID, r.retry
+			raw := bytes.TrimSuffix(data.Bytes(), []byte("\n"))
+			if v == nil {
+				e.Data = raw
+				return &e, nil
+			}
+			if err = r.marshaler.Unmarshal(raw, v); err != nil {
+				return nil, err
+			}
+			e.Data = v
+			return &e, nil
+		}
+		if line[0] == ':' {
+			continue
+		}
+		name, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch name {
+		case "event":
+			e.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				r.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				r.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// readLine reads a line terminated by "\r\n", "\n" or "\r", the line break excluded.
+// The unterminated last line is discarded.
+func (r *Reader) readLine() (string, error) {
+	var line []byte
+	for {
+		b, err := r.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		skipLF := r.skipLF
+		r.skipLF = false
+		switch b {
+		case '\n':
+			if skipLF {
+				continue
+			}
+			return string(line), nil
+		case '\r':
+			r.skipLF = true
+			return string(line), nil
+		}
+		line = append(line, b)
+	}
+}
@@ -0,0 +1,167 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/things-go/encoding/json"
+	"github.com/things-go/encoding/yaml"
+)
+
+type progress struct {
+	Step  int    `json:"step" yaml:"step"`
+	Title string `json:"title" yaml:"title"`
+}
+
+func TestWriter(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	w := httptest.NewRecorder()
+
+	sw := NewWriter(w, r, &json.Codec{})
+	if !w.Flushed {
+		t.Errorf("NewWriter() should flush the headers")
+	}
+	if got := w.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("Content-Type got = %q, want %q", got, ContentType)
+	}
+	if err := sw.Send(&Event{ID: "1", Event: "progress", Retry: 3 * time.Second, Data: &progress{1, "a"}}); err != nil {
+		t.Fatalf("Send() failed with %v; want success", err)
+	}
+	if err := sw.Comment("keep-alive"); err != nil {
+		t.Fatalf("Comment() failed with %v; want success", err)
+	}
+	if err := sw.Encode(&progress{2, "b"}); err != nil {
+		t.Fatalf("Encode() failed with %v; want success", err)
+	}
+	want := "event: progress\nid: 1\nretry: 3000\ndata: {\"step\":1,\"title\":\"a\"}\n\n" +
+		": keep-alive\n\n" +
+		"data: {\"step\":2,\"title\":\"b\"}\n\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("body got = %q, want %q", got, want)
+	}
+
+	if err := sw.Send(&Event{ID: "1\n2"}); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("Send() error = %v, want %v", err, ErrInvalidField)
+	}
+}
+
+func TestWriter_MultiLine(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	w := httptest.NewRecorder()
+
+	sw := NewWriter(w, r, &yaml.Codec{})
+	if err := sw.Encode(&progress{1, "line1\nline2"}); err != nil {
+		t.Fatalf("Encode() failed with %v; want success", err)
+	}
+	for _, line := range strings.SplitAfter(strings.TrimSuffix(w.Body.String(), "\n\n"), "\n") {
+		if !strings.HasPrefix(line, "data: ") && !strings.HasPrefix(line, "data:\n") {
+			t.Errorf("line %q should be a data field", line)
+		}
+	}
+
+	var got progress
+	if err := NewReader(w.Body, &yaml.Codec{}).Decode(&got); err != nil {
+		t.Fatalf("Decode() failed with %v; want success", err)
+	}
+	if want := (progress{1, "line1\nline2"}); got != want {
+		t.Errorf("Decode() got = %v, want %v", got, want)
+	}
+}
+
+func TestWriter_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	sw := NewWriter(w, r, &json.Codec{})
+
+	events := make(chan *Event, 1)
+	events <- &Event{Data: 1}
+	done := make(chan error)
+	go func() { done <- sw.Stream(events) }()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Stream() error = %v, want %v", err, context.Canceled)
+	}
+	if err := sw.Encode(2); !errors.Is(err, context.Canceled) {
+		t.Errorf("Encode() error = %v, want %v", err, context.Canceled)
+	}
+	if want := "data: 1\n\n"; w.Body.String() != want {
+		t.Errorf("body got = %q, want %q", w.Body.String(), want)
+	}
+}
+
+func TestReader(t *testing.T) {
+	stream := ": comment\n\n" +
+		"retry: 1000\n\n" +
+		"event: add\rid: 7\r\ndata: {\"step\":1,\r\ndata:\"title\":\"a\"}\n\n" +
+		"id: 8\nretry: abc\nunknown: x\ndata: {\"step\":2}\n\n" +
+		"data: {\"step\":3}" // incomplete.
+
+	r := NewReader(strings.NewReader(stream), &json.Codec{})
+
+	var got progress
+	e, err := r.ReadEvent(&got)
+	if err != nil {
+		t.Fatalf("ReadEvent() failed with %v; want success", err)
+	}
+	want := &Event{ID: "7", Event: "add", Retry: time.Second, Data: &progress{1, "a"}}
+	if !reflect.DeepEqual(e, want) {
+		t.Errorf("ReadEvent() got = %+v, want %+v", e, want)
+	}
+
+	e, err = r.ReadEvent(nil)
+	if err != nil {
+		t.Fatalf("ReadEvent() failed with %v; want success", err)
+	}
+	want = &Event{ID: "8", Retry: time.Second, Data: []byte(`{"step":2}`)}
+	if !reflect.DeepEqual(e, want) {
+		t.Errorf("ReadEvent() got = %+v, want %+v", e, want)
+	}
+
+	if _, err = r.ReadEvent(nil); !errors.Is(err, io.EOF) {
+		t.Errorf("ReadEvent() error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := NewWriter(w, r, &json.Codec{})
+		for i := 1; i <= 3; i++ {
+			_ = sw.Send(&Event{Event: "progress", Data: &progress{Step: i}})
+		}
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	r := NewReader(resp.Body, &json.Codec{})
+	for i := 1; ; i++ {
+		var got progress
+		e, err := r.ReadEvent(&got)
+		if errors.Is(err, io.EOF) {
+			if i != 4 {
+				t.Errorf("got %d events, want 3", i-1)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Event != "progress" || got.Step != i {
+			t.Errorf("ReadEvent() got = %+v %+v", e, got)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package encoding
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/things-go/encoding/codec"
+)
+
+// RenderStream writes the records of seq as a stream with the outbound marshalers for this request,
+// negotiated like Render. A codec.StreamMarshaler writes the records one at a time, e.g. as
+// a JSON array, NDJSON, length-delimited protobuf or msgpack values, other marshalers write
+// all the records as a slice after the iteration.
+//
+// The first bytes are buffered and compressed like Render. If seq yields an error, or a record
+// fails to encode, the iteration stops and the error is returned, if the response is not committed
+// yet, nothing is written and the `Content-Type` is removed, otherwise the stream is left unfinished,
+// e.g. a JSON array without the end, so the client can tell the stream is broken.
+func (r *Encoding) RenderStream(w http.ResponseWriter, req *http.Request, seq codec.Seq2[any, error]) error {
+	reg := r.load()
+	mediaType, marshaller, err := reg.negotiateOutbound(req)
+	if err != nil {
+		return err
+	}
+	header := w.Header()
+	header.Set(contentTypeHeader, contentTypeFor(mediaType, marshaller, nil))
+	addVary(header, acceptHeader)
+
+	buf := newResponseBuffer(w)
+	defer buf.Release()
+	if coding, compressor := reg.responseCompressor(req, header, nil); compressor != nil {
+		buf.coding, buf.compressor, buf.minSize = coding, compressor, reg.compressMinSize
+	}
+
+	var enc codec.StreamEncoder
+	if sm, ok := marshaller.(codec.StreamMarshaler); ok {
+		enc = sm.NewStreamEncoder(buf)
+	} else {
+		enc = &sliceEncoder{enc: marshaller.NewEncoder(buf)}
+	}
+	seq(func(v any, e error) bool {
+		if e == nil {
+			e = enc.Encode(v)
+		}
+		err = e
+		return err == nil
+	})
+	if err == nil {
+		err = enc.Close()
+	}
+	if err != nil {
+		if !buf.Committed() {
+			header.Del(contentTypeHeader)
+		}
+		return err
+	}
+	return buf.Close()
+}
+
+// sliceEncoder collects the records, and encodes them as a slice when closed.
+type sliceEncoder struct {
+	enc     codec.Encoder
+	records []any
+}
+
+func (e *sliceEncoder) Encode(v any) error {
+	e.records = append(e.records, v)
+	return nil
+}
+
+func (e *sliceEncoder) Close() error {
+	if e.records == nil {
+		e.records = []any{}
+	}
+	return e.enc.Encode(e.records)
+}
+
+// BindStream binds the request body as a stream of records with the inbound codec.StreamMarshaler
+// for this request, negotiated like Bind, fn reads the records one at a time from the Decoder
+// until io.EOF, see codec.Iter. The request body is decompressed and limited like Bind.
+// The request without a body (see Bind) is an empty stream.
+// It reports ErrUnsupportedMediaType wrapped in *MediaTypeError if the marshaler is not a
+// codec.StreamMarshaler, the decode failures are reported as *codec.FieldError or codec.FieldErrors
+// with codec.SourceBody.
+//
+//	err := r.BindStream(req, func(dec codec.Decoder) error {
+//		for record, err := range codec.Iter[*pb.Record](dec) {
+//			...
+//		}
+//	})
+func (r *Encoding) BindStream(req *http.Request, fn func(dec codec.Decoder) error) error {
+	reg := r.load()
+	withBody, err := hasBody(req)
+	if err != nil {
+		return err
+	}
+	if !withBody {
+		return fn(codec.DecoderFunc(func(any) error { return io.EOF }))
+	}
+	contentType, marshaller, err := reg.negotiateInbound(req)
+	if err != nil {
+		return err
+	}
+	sm, ok := marshaller.(codec.StreamMarshaler)
+	if !ok {
+		return &MediaTypeError{
+			Err:       ErrUnsupportedMediaType,
+			MediaType: contentType,
+			Supported: reg.streamable(),
+		}
+	}
+	if err = reg.decompressBody(req); err != nil {
+		return err
+	}
+	limits := reg.limitsFor(contentType)
+	body := limits.limitBody(req)
+	dec := sm.NewStreamDecoder(req.Body)
+	return fn(codec.DecoderFunc(func(v any) error {
+		return codec.SetSource(body.check(dec.Decode(v)), codec.SourceBody)
+	}))
+}
+
+// streamable returns the sorted registered MIME types of the codec.StreamMarshaler.
+func (r *registry) streamable() []string {
+	var mimes []string
+	for _, mime := range r.registered() {
+		if _, ok := r.mimeMap[mime].(codec.StreamMarshaler); ok {
+			mimes = append(mimes, mime)
+		}
+	}
+	return mimes
+}
@@ -0,0 +1,218 @@
+package encoding
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/things-go/encoding/codec"
+	"github.com/things-go/encoding/msgpack"
+	"github.com/things-go/encoding/ndjson"
+	"github.com/things-go/encoding/xml"
+)
+
+func newStreamEncoding() *Encoding {
+	r := New()
+	_ = r.Register(MIMENDJSON, &ndjson.Codec{})
+	_ = r.Register(MIMEMSGPACK, &msgpack.Codec{})
+	_ = r.Register(MIMEXML, &xml.Codec{})
+	return r
+}
+
+func Test_Encoding_RenderStream(t *testing.T) {
+	records := []*TestMode{{Id: "1"}, {Id: "2"}}
+
+	tests := []struct {
+		name            string
+		accept          string
+		wantContentType string
+		want            func() []byte
+	}{
+		{
+			"json array",
+			MIMEJSON,
+			"application/json; charset=utf-8",
+			func() []byte { return []byte(`[{"id":"1","name":""},{"id":"2","name":""}]` + "\n") },
+		},
+		{
+			"ndjson",
+			MIMENDJSON,
+			MIMENDJSON,
+			func() []byte { return []byte(`{"id":"1","name":""}` + "\n" + `{"id":"2","name":""}` + "\n") },
+		},
+		{
+			"msgpack",
+			MIMEMSGPACK,
+			"application/x-msgpack; charset=utf-8",
+			func() []byte {
+				var buf bytes.Buffer
+				enc := (&msgpack.Codec{}).NewEncoder(&buf)
+				for _, v := range records {
+					_ = enc.Encode(v)
+				}
+				return buf.Bytes()
+			},
+		},
+		{
+			"not a stream marshaler",
+			MIMEXML,
+			"application/xml; charset=utf-8",
+			func() []byte {
+				b, _ := (&xml.Codec{}).Marshal([]any{records[0], records[1]})
+				return b
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			r.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			require.NoError(t, newStreamEncoding().RenderStream(w, r, codec.SliceSeq(records)))
+			require.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+			require.Equal(t, tt.want(), w.Body.Bytes())
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		errBroken := errors.New("broken")
+		r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		w := httptest.NewRecorder()
+		err := New().RenderStream(w, r, func(yield func(any, error) bool) {
+			if yield(records[0], nil) {
+				yield(nil, errBroken)
+			}
+		})
+		require.ErrorIs(t, err, errBroken)
+		require.Empty(t, w.Header().Get("Content-Type"))
+		require.Zero(t, w.Body.Len())
+	})
+	t.Run("error after committed compressed", func(t *testing.T) {
+		errBroken := errors.New("broken")
+		large := &TestMode{Name: strings.Repeat("a", renderBufferSize)}
+		registry := New().SetCompressMinSize(0)
+		r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		r.Header.Set("Accept-Encoding", EncodingGzip)
+		w := httptest.NewRecorder()
+		err := registry.RenderStream(w, r, func(yield func(any, error) bool) {
+			if yield(large, nil) && yield(large, nil) {
+				yield(nil, errBroken)
+			}
+		})
+		require.ErrorIs(t, err, errBroken)
+		require.Equal(t, EncodingGzip, w.Header().Get("Content-Encoding"))
+		zr, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		_, err = io.ReadAll(zr)
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF, "the truncated body should not be finalized")
+
+		// the discarded writer is reused.
+		w = httptest.NewRecorder()
+		require.NoError(t, registry.RenderStream(w, r, codec.SliceSeq(records)))
+		zr, err = gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		b, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		require.Equal(t, `[{"id":"1","name":""},{"id":"2","name":""}]`+"\n", string(b))
+	})
+	t.Run("not acceptable", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		r.Header.Set("Accept", "text/csv")
+		w := httptest.NewRecorder()
+		err := New().SetStrict(true).RenderStream(w, r, codec.SliceSeq(records))
+		require.ErrorIs(t, err, ErrNotAcceptable)
+	})
+}
+
+func Test_Encoding_BindStream(t *testing.T) {
+	collect := func(dec codec.Decoder) ([]string, error) {
+		var ids []string
+		var err error
+		codec.Iter[*TestMode](dec)(func(v *TestMode, e error) bool {
+			if e != nil {
+				err = e
+				return false
+			}
+			ids = append(ids, v.Id)
+			return true
+		})
+		return ids, err
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		want        []string
+		wantErr     error
+	}{
+		{"json array", http.MethodPost, MIMEJSON, `[{"id":"1"},{"id":"2"}]`, []string{"1", "2"}, nil},
+		{"ndjson", http.MethodPost, MIMENDJSON, `{"id":"1"}` + "\n" + `{"id":"2"}` + "\n", []string{"1", "2"}, nil},
+		{"no body", http.MethodPost, MIMEJSON, "", nil, nil},
+		{"safe method", http.MethodGet, MIMEJSON, `[{"id":"1"}]`, nil, nil},
+		{"not a stream marshaler", http.MethodPost, MIMEXML, `<TestMode></TestMode>`, nil, ErrUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "http://example.com", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			var got []string
+			err := newStreamEncoding().BindStream(r, func(dec codec.Decoder) error {
+				var err error
+				got, err = collect(dec)
+				return err
+			})
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				var mediaTypeErr *MediaTypeError
+				require.ErrorAs(t, err, &mediaTypeErr)
+				require.Equal(t, []string{MIMEJSON, MIMEMSGPACK, MIMENDJSON}, mediaTypeErr.Supported)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("field error", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "http://example.com", strings.NewReader(`[{"id":"1"},{"id":2}]`))
+		r.Header.Set("Content-Type", MIMEJSON)
+		err := New().BindStream(r, func(dec codec.Decoder) error {
+			_, err := collect(dec)
+			return err
+		})
+		var fe *codec.FieldError
+		require.ErrorAs(t, err, &fe)
+		require.Equal(t, codec.SourceBody, fe.Source)
+		require.Equal(t, "[1].id", fe.Field)
+	})
+	t.Run("limit", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "http://example.com", strings.NewReader(`[{"id":"1"},{"id":"2"}]`))
+		r.Header.Set("Content-Type", MIMEJSON)
+		err := New().SetLimits(Limits{MaxBodySize: 16}).BindStream(r, func(dec codec.Decoder) error {
+			_, err := collect(dec)
+			return err
+		})
+		require.ErrorIs(t, err, ErrBodyTooLarge)
+	})
+	t.Run("stop early", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "http://example.com", strings.NewReader(`[{"id":"1"},{"id":"2"}]`))
+		r.Header.Set("Content-Type", MIMEJSON)
+		err := New().BindStream(r, func(dec codec.Decoder) error {
+			v := &TestMode{}
+			if err := dec.Decode(v); err != nil {
+				return err
+			}
+			return io.ErrShortBuffer
+		})
+		require.ErrorIs(t, err, io.ErrShortBuffer)
+	})
+}
@@ -1,15 +1,28 @@
 package toml
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"regexp"
+	"strings"
 
 	"github.com/pelletier/go-toml/v2"
 
 	"github.com/things-go/encoding/codec"
 )
 
-// Codec is a Codec implementation with yaml.
-type Codec struct{}
+// Codec is a Codec implementation with toml.
+// The NewDecoder returns a DecoderWrapper, which embeds the *toml.Decoder.
+// NOTE: the NewDecoder no longer returns *toml.Decoder, a type assertion to *toml.Decoder
+// fails, assert DecoderWrapper instead.
+// The decode failures are reported as *codec.FieldError or codec.FieldErrors.
+type Codec struct {
+	// DisallowUnknownFields reports the keys which match no struct fields as codec.FieldErrors,
+	// with the dotted field paths and the line numbers, instead of ignoring them.
+	DisallowUnknownFields bool
+}
 
 // ContentType always Returns "application/yaml; charset=utf-8".
 func (*Codec) ContentType(_ any) string {
@@ -18,11 +31,69 @@ func (*Codec) ContentType(_ any) string {
 func (*Codec) Marshal(v any) ([]byte, error) {
 	return toml.Marshal(v)
 }
-func (*Codec) Unmarshal(data []byte, v any) error {
-	return toml.Unmarshal(data, v)
+func (c *Codec) Unmarshal(data []byte, v any) error {
+	if c.DisallowUnknownFields {
+		return c.NewDecoder(bytes.NewReader(data)).Decode(v)
+	}
+	return wrapError(toml.Unmarshal(data, v))
+}
+func (c *Codec) NewDecoder(r io.Reader) codec.Decoder {
+	decoder := toml.NewDecoder(r)
+	if c.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	return DecoderWrapper{decoder}
+}
+
+// DecoderWrapper is a wrapper around a *toml.Decoder that reports
+// the decode failures as *codec.FieldError or codec.FieldErrors.
+type DecoderWrapper struct {
+	*toml.Decoder
+}
+
+// Decode wraps the embedded decoder's Decode method.
+func (d DecoderWrapper) Decode(v any) error {
+	return wrapError(d.Decoder.Decode(v))
 }
-func (*Codec) NewDecoder(r io.Reader) codec.Decoder {
-	return toml.NewDecoder(r)
+
+// toml reports a type mismatch like "toml: cannot decode TOML string into struct field main.T.Id of type int".
+var typeMismatchRegexp = regexp.MustCompile(`cannot decode TOML \S+ into (?:struct field \S*\.(\w+)|a Go value) of type (.+)$`)
+
+// wrapError converts the decode failures into *codec.FieldError or codec.FieldErrors,
+// other errors (e.g. the reader's error) are returned as is.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var strictErr *toml.StrictMissingError
+	var decodeErr *toml.DecodeError
+	switch {
+	case errors.As(err, &strictErr):
+		errs := make(codec.FieldErrors, 0, len(strictErr.Errors))
+		for i := range strictErr.Errors {
+			e := &strictErr.Errors[i]
+			field := strings.Join(e.Key(), ".")
+			row, _ := e.Position()
+			errs = append(errs, &codec.FieldError{Field: field, Err: fmt.Errorf("line %d: unknown field %q: %w", row, field, e)})
+		}
+		return errs.Err()
+	case errors.As(err, &decodeErr):
+		fe := &codec.FieldError{Field: strings.Join(decodeErr.Key(), "."), Err: err}
+		if m := typeMismatchRegexp.FindStringSubmatch(decodeErr.Error()); m != nil {
+			if fe.Field == "" {
+				fe.Field = m[1] // the Go struct field name.
+			}
+			fe.Type = m[2]
+		}
+		return fe
+	}
+	if m := typeMismatchRegexp.FindStringSubmatch(err.Error()); m != nil {
+		return &codec.FieldError{Field: m[1], Type: m[2], Err: err}
+	}
+	if strings.HasPrefix(err.Error(), "toml:") {
+		return &codec.FieldError{Err: err}
+	}
+	return err
 }
 func (*Codec) NewEncoder(w io.Writer) codec.Encoder {
 	return toml.NewEncoder(w)
@@ -2,10 +2,13 @@ package toml
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/things-go/encoding/codec"
 )
 
 func TestCodec_ContentType(t *testing.T) {
@@ -51,3 +54,70 @@ func TestCodec_Encoder_Decoder(t *testing.T) {
 
 	assert.Equal(t, want, got)
 }
+
+func TestCodec_DecodeFieldError(t *testing.T) {
+	type value struct {
+		N int `toml:"n"`
+	}
+	m := Codec{}
+	tests := []struct {
+		name string
+		data string
+		want codec.FieldError
+	}{
+		{"type mismatch", "n = \"abc\"\n", codec.FieldError{Field: "N", Type: "int"}},
+		{"syntax", "n = \n", codec.FieldError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, err := range []error{
+				m.Unmarshal([]byte(tt.data), &value{}),
+				m.NewDecoder(strings.NewReader(tt.data)).Decode(&value{}),
+			} {
+				var fe *codec.FieldError
+				require.ErrorAs(t, err, &fe)
+				require.Equal(t, tt.want.Field, fe.Field)
+				require.Equal(t, tt.want.Type, fe.Type)
+			}
+		})
+	}
+	t.Run("strict", func(t *testing.T) {
+		d := m.NewDecoder(strings.NewReader("n = 1\nx = 1\ny = 2\n")).(DecoderWrapper)
+		d.DisallowUnknownFields()
+		var errs codec.FieldErrors
+		require.ErrorAs(t, d.Decode(&value{}), &errs)
+		require.Len(t, errs, 2)
+		require.Equal(t, "x", errs[0].Field)
+		require.Equal(t, "y", errs[1].Field)
+	})
+}
+
+func TestCodec_DisallowUnknownFields(t *testing.T) {
+	type server struct {
+		Host string `toml:"host"`
+	}
+	type config struct {
+		Name   string `toml:"name"`
+		Server server `toml:"server"`
+	}
+	data := "name = \"foo\"\ntimout = 5\n\n[server]\nhost = \"a\"\nhots = \"b\"\n"
+
+	m := Codec{DisallowUnknownFields: true}
+	for _, err := range []error{
+		m.Unmarshal([]byte(data), &config{}),
+		m.NewDecoder(strings.NewReader(data)).Decode(&config{}),
+	} {
+		var errs codec.FieldErrors
+		require.ErrorAs(t, err, &errs)
+		require.Len(t, errs, 2)
+		require.Equal(t, "timout", errs[0].Field)
+		require.Contains(t, errs[0].Error(), "line 2:")
+		require.Equal(t, "server.hots", errs[1].Field)
+		require.Contains(t, errs[1].Error(), "line 6:")
+	}
+
+	require.NoError(t, (&Codec{}).Unmarshal([]byte(data), &config{}))
+	got := &config{}
+	require.NoError(t, m.Unmarshal([]byte("name = \"foo\"\n[server]\nhost = \"a\"\n"), got))
+	require.Equal(t, &config{Name: "foo", Server: server{Host: "a"}}, got)
+}
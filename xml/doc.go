@@ -0,0 +1,46 @@
+// Package xml implements the XML codec.
+// Other than the proto messages, the values are marshaled with the standard "encoding/xml"
+// package of Golang.
+//
+// The proto messages are marshaled via protoreflect with the mapping:
+// the root element is named by Codec.RootName, default the message name, any root element
+// name is accepted when unmarshaling. A field is an element named by the JSON name, or the
+// proto name if Codec.UseProtoNames, the unpopulated fields are omitted. A repeated field is
+// the repeated elements, a map field is an element with the "entry" elements of the "key" and
+// then "value" elements, ordered by the keys. An enum is the name, or the number if
+// Codec.UseEnumNumbers or the number has no name. Bytes is the standard base64 encoding,
+// a float which is not finite is "NaN", "Infinity" or "-Infinity". Timestamp is RFC 3339 text,
+// Duration is the seconds with the "s" suffix, e.g. "1.5s", a wrapper is the text of the value,
+// FieldMask is the comma-separated paths, and Any is the element with the "type" attribute of
+// the type URL and the fields of the resolved message.
+//
+//	<User>
+//	  <id>1</id>
+//	  <roles>admin</roles>
+//	  <roles>dev</roles>
+//	  <labels><entry><key>env</key><value>prod</value></entry></labels>
+//	  <status>STATUS_ACTIVE</status>
+//	  <createTime>2024-01-02T03:04:05Z</createTime>
+//	</User>
+//
+// The maps and the slices of the interfaces or maps are the element trees: the root element
+// is named by Codec.RootName, default "root", a map entry is the child element named by the
+// key, or the "entry" element with the key attribute if Codec.MapKeyAttr, a slice value of
+// a map is the repeated elements, and a slice is the "item" elements. The "@" prefixed keys
+// are the attributes and the "#text" key is the text content. Any XML is unmarshaled into
+// a *any, *map[string]any or *[]any the same way, an element with neither the child elements
+// nor the attributes is the text, the repeated child elements are []any, and the names are
+// the local names without the namespaces.
+//
+//	<root>
+//	  <name>foo</name>
+//	  <tags>a</tags>
+//	  <tags>b</tags>
+//	  <owner id="1">bar</owner>
+//	</root>
+//
+// is map[string]any{"name": "foo", "tags": []any{"a", "b"}, "owner": map[string]any{"@id": "1", "#text": "bar"}}.
+//
+// The decode failures are reported as *codec.FieldError, whose Field is empty since
+// "encoding/xml" does not report the fields, except the failures of the proto messages and maps.
+package xml
@@ -0,0 +1,371 @@
+package xml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/things-go/encoding/codec"
+)
+
+// defaultRootName is the root element name of the maps and slices if Codec.RootName is empty.
+const defaultRootName = "root"
+
+// itemName is the element name of the slice elements, except the map values.
+const itemName = "item"
+
+// attrPrefix and textKey are the keys of the attributes and the text content in the decoded maps.
+const (
+	attrPrefix = "@"
+	textKey    = "#text"
+)
+
+// isGeneric reports whether v is a map or a slice of the interfaces or maps,
+// which are encoded as the element trees.
+func isGeneric(v any) bool {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Slice, reflect.Array:
+		k := rv.Type().Elem().Kind()
+		return k == reflect.Interface || k == reflect.Map
+	}
+	return false
+}
+
+// rootStart returns the root element with the namespace declarations.
+func (c *Codec) rootStart(name string) xml.StartElement {
+	if c.RootName != "" {
+		name = c.RootName
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	prefixes := make([]string, 0, len(c.Namespaces))
+	for prefix := range c.Namespaces {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		local := "xmlns"
+		if prefix != "" {
+			local += ":" + prefix
+		}
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: local}, Value: c.Namespaces[prefix]})
+	}
+	return start
+}
+
+// encodeGeneric writes the map or slice as the root element.
+func (c *Codec) encodeGeneric(e *xml.Encoder, v any) error {
+	if err := c.encodeTree(e, c.rootStart(defaultRootName), reflect.ValueOf(v)); err != nil {
+		return err
+	}
+	return e.Flush()
+}
+
+// encodeTree writes the value as the element, a map is the child elements named by the keys,
+// the "@" prefixed keys are the attributes and the "#text" key is the text content,
+// a slice value of the map is the repeated elements, other slices are the "item" elements.
+func (c *Codec) encodeTree(e *xml.Encoder, start xml.StartElement, rv reflect.Value) error {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return e.EncodeElement("", start)
+		}
+		if m, ok := rv.Interface().(proto.Message); ok {
+			return c.encodeMessage(e, start, m.ProtoReflect())
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		return c.encodeTreeMap(e, start, rv)
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		item := xml.StartElement{Name: xml.Name{Local: itemName}}
+		for i := 0; i < rv.Len(); i++ {
+			if err := c.encodeTree(e, item, rv.Index(i)); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	}
+	return e.EncodeElement(rv.Interface(), start)
+}
+
+func (c *Codec) encodeTreeMap(e *xml.Encoder, start xml.StartElement, rv reflect.Value) error {
+	type entry struct {
+		key   string
+		value reflect.Value
+	}
+	entries := make([]entry, 0, rv.Len())
+	var text *reflect.Value
+	iter := rv.MapRange()
+	for iter.Next() {
+		key := fmt.Sprint(iter.Key().Interface())
+		value := iter.Value()
+		switch {
+		case key == textKey:
+			text = &value
+		case strings.HasPrefix(key, attrPrefix):
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: key[len(attrPrefix):]}, Value: fmt.Sprint(value.Interface())})
+		default:
+			entries = append(entries, entry{key, value})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	sort.SliceStable(start.Attr, func(i, j int) bool { return start.Attr[i].Name.Local < start.Attr[j].Name.Local })
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if text != nil {
+		if err := e.EncodeToken(xml.CharData(fmt.Sprint(text.Interface()))); err != nil {
+			return err
+		}
+	}
+	for _, ent := range entries {
+		child := xml.StartElement{Name: xml.Name{Local: ent.key}}
+		if c.MapKeyAttr != "" {
+			child = xml.StartElement{
+				Name: xml.Name{Local: "entry"},
+				Attr: []xml.Attr{{Name: xml.Name{Local: c.MapKeyAttr}, Value: ent.key}},
+			}
+		} else if !isValidName(ent.key) {
+			return fmt.Errorf("xml: invalid element name %q of the map key, see Codec.MapKeyAttr", ent.key)
+		}
+		value := ent.value
+		for value.Kind() == reflect.Interface && !value.IsNil() {
+			value = value.Elem()
+		}
+		if (value.Kind() == reflect.Slice || value.Kind() == reflect.Array) && value.Type().Elem().Kind() != reflect.Uint8 {
+			for i := 0; i < value.Len(); i++ {
+				if err := c.encodeTree(e, child, value.Index(i)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := c.encodeTree(e, child, value); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// isValidName reports whether the name is a valid XML element name.
+func isValidName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		if unicode.IsLetter(r) || r == '_' || r == ':' {
+			continue
+		}
+		if i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// genericTarget returns the value pointed by v if it is a map with the string keys, an empty
+// interface or a slice, whose values are the interfaces or strings, which are decoded from
+// the element trees.
+func genericTarget(v any) (reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return reflect.Value{}, false
+	}
+	rv = rv.Elem()
+	t := rv.Type()
+	switch t.Kind() {
+	case reflect.Interface:
+		return rv, t.NumMethod() == 0
+	case reflect.Map:
+		return rv, t.Key().Kind() == reflect.String && isTreeValue(t.Elem())
+	case reflect.Slice:
+		return rv, isTreeValue(t.Elem())
+	}
+	return reflect.Value{}, false
+}
+
+func isTreeValue(t reflect.Type) bool {
+	return (t.Kind() == reflect.Interface && t.NumMethod() == 0) || t.Kind() == reflect.String
+}
+
+// decodeGeneric reads the next root element into the map, interface or slice.
+// The root element is decoded into an interface, a map of the children,
+// or a slice of the children values in order.
+func (c *Codec) decodeGeneric(d *xml.Decoder, rv reflect.Value) error {
+	var start xml.StartElement
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		var ok bool
+		if start, ok = tok.(xml.StartElement); ok {
+			break
+		}
+	}
+	if rv.Kind() == reflect.Slice {
+		values, err := c.decodeChildren(d)
+		if err != nil {
+			return err
+		}
+		sl := reflect.MakeSlice(rv.Type(), 0, len(values))
+		for i, value := range values {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if err = assignTree(elem, value, fmt.Sprintf("[%d]", i)); err != nil {
+				return err
+			}
+			sl = reflect.Append(sl, elem)
+		}
+		rv.Set(sl)
+		return nil
+	}
+
+	value, err := c.decodeElement(d, start)
+	if err != nil {
+		return err
+	}
+	if rv.Kind() == reflect.Interface {
+		rv.Set(reflect.ValueOf(value))
+		return nil
+	}
+	m, ok := value.(map[string]any)
+	if !ok {
+		if s, _ := value.(string); strings.TrimSpace(s) != "" {
+			return &codec.FieldError{Value: s, Type: rv.Type().String(), Err: fmt.Errorf("xml: expect the child elements of %q", start.Name.Local)}
+		}
+		m = map[string]any{}
+	}
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMapWithSize(rv.Type(), len(m)))
+	}
+	for k, v := range m {
+		elem := reflect.New(rv.Type().Elem()).Elem()
+		if err = assignTree(elem, v, k); err != nil {
+			return err
+		}
+		rv.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), elem)
+	}
+	return nil
+}
+
+// assignTree assigns the decoded value to the interface or string.
+func assignTree(rv reflect.Value, value any, field string) error {
+	if rv.Kind() == reflect.Interface {
+		if value != nil {
+			rv.Set(reflect.ValueOf(value))
+		}
+		return nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return &codec.FieldError{Field: field, Type: rv.Type().String(), Err: fmt.Errorf("xml: expect the text, got %T", value)}
+	}
+	rv.SetString(s)
+	return nil
+}
+
+// decodeElement reads the content of the start element, an element with neither the child elements
+// nor the attributes is the text, otherwise a map[string]any of the children, the repeated children
+// are []any. The attributes are the "@" prefixed keys, the non-blank text content is the "#text" key.
+// The child elements named "entry" with the Codec.MapKeyAttr attribute are keyed by the attribute.
+func (c *Codec) decodeElement(d *xml.Decoder, start xml.StartElement) (any, error) {
+	m := make(map[string]any)
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		if c.MapKeyAttr != "" && attr.Name.Local == c.MapKeyAttr && start.Name.Local == "entry" {
+			continue
+		}
+		m[attrPrefix+attr.Name.Local] = attr.Value
+	}
+	var text strings.Builder
+	repeated := make(map[string]bool)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.CharData:
+			text.Write(tok)
+		case xml.StartElement:
+			key := c.childKey(tok)
+			value, err := c.decodeElement(d, tok)
+			if err != nil {
+				return nil, err
+			}
+			if old, ok := m[key]; !ok {
+				m[key] = value
+			} else if repeated[key] {
+				m[key] = append(old.([]any), value)
+			} else {
+				m[key] = []any{old, value}
+				repeated[key] = true
+			}
+		case xml.EndElement:
+			if len(m) == 0 {
+				return text.String(), nil
+			}
+			if s := strings.TrimSpace(text.String()); s != "" {
+				m[textKey] = s
+			}
+			return m, nil
+		}
+	}
+}
+
+// decodeChildren reads the values of the child elements in order until the end of the element.
+func (c *Codec) decodeChildren(d *xml.Decoder) ([]any, error) {
+	values := make([]any, 0)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			value, err := c.decodeElement(d, tok)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		case xml.EndElement:
+			return values, nil
+		}
+	}
+}
+
+// childKey returns the map key of the child element.
+func (c *Codec) childKey(start xml.StartElement) string {
+	if c.MapKeyAttr != "" && start.Name.Local == "entry" {
+		for _, attr := range start.Attr {
+			if attr.Name.Local == c.MapKeyAttr {
+				return attr.Value
+			}
+		}
+	}
+	return start.Name.Local
+}
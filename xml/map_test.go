@@ -0,0 +1,163 @@
+package xml
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/things-go/encoding/codec"
+	"github.com/things-go/encoding/testdata/examplepb"
+)
+
+func TestCodec_MarshalMap(t *testing.T) {
+	tests := []struct {
+		name  string
+		codec *Codec
+		value any
+		want  string
+	}{
+		{
+			name:  "map",
+			codec: &Codec{},
+			value: map[string]any{"name": "foo", "tags": []any{"a", "b"}, "owner": map[string]any{"@id": 1, "#text": "bar"}},
+			want:  `<root><name>foo</name><owner id="1">bar</owner><tags>a</tags><tags>b</tags></root>`,
+		},
+		{
+			name:  "slice",
+			codec: &Codec{RootName: "list"},
+			value: []any{1, "x", map[string]int{"a": 1}},
+			want:  `<list><item>1</item><item>x</item><item><a>1</a></item></list>`,
+		},
+		{
+			name:  "key attribute",
+			codec: &Codec{MapKeyAttr: "key"},
+			value: map[string]int{"a b": 1, "c": 2},
+			want:  `<root><entry key="a b">1</entry><entry key="c">2</entry></root>`,
+		},
+		{
+			name:  "proto value",
+			codec: &Codec{},
+			value: map[string]any{"msg": &examplepb.SimpleMessage{Id: "1"}},
+			want:  `<root><msg><id>1</id></msg></root>`,
+		},
+		{
+			name:  "namespaces",
+			codec: &Codec{Namespaces: map[string]string{"": "urn:a", "x": "urn:x"}},
+			value: map[string]any{"x:name": "foo"},
+			want:  `<root xmlns="urn:a" xmlns:x="urn:x"><x:name>foo</x:name></root>`,
+		},
+		{
+			name:  "indent and header",
+			codec: &Codec{Indent: "  ", Header: true},
+			value: map[string]any{"a": "1", "b": map[string]any{"c": "2"}},
+			want:  "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<root>\n  <a>1</a>\n  <b>\n    <c>2</c>\n  </b>\n</root>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.codec.Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("Marshal(%v) failed with %v; want success", tt.value, err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal(%v) = %q; want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid name", func(t *testing.T) {
+		if _, err := (&Codec{}).Marshal(map[string]int{"a b": 1}); err == nil {
+			t.Errorf("Marshal() should returned an error of the invalid element name")
+		}
+	})
+}
+
+func TestCodec_UnmarshalMap(t *testing.T) {
+	data := []byte(`<root xmlns:x="urn:x"><x:name>foo</x:name><tags>a</tags><tags>b</tags><owner id="1">bar</owner><empty/></root>`)
+	want := map[string]any{
+		"name":  "foo",
+		"tags":  []any{"a", "b"},
+		"owner": map[string]any{"@id": "1", "#text": "bar"},
+		"empty": "",
+	}
+
+	t.Run("any", func(t *testing.T) {
+		var got any
+		if err := (&Codec{}).Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal(%q) failed with %v; want success", data, err)
+		}
+		if diff := cmp.Diff(got, any(want)); diff != "" {
+			t.Errorf("Unmarshal(%q) differs:\n%s", data, diff)
+		}
+	})
+	t.Run("map", func(t *testing.T) {
+		got := map[string]any{}
+		if err := (&Codec{}).Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal(%q) failed with %v; want success", data, err)
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("Unmarshal(%q) differs:\n%s", data, diff)
+		}
+	})
+	t.Run("slice", func(t *testing.T) {
+		var got []any
+		data := []byte(`<list><item>1</item><item><a>1</a></item></list>`)
+		if err := (&Codec{}).Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal(%q) failed with %v; want success", data, err)
+		}
+		if diff := cmp.Diff(got, []any{"1", map[string]any{"a": "1"}}); diff != "" {
+			t.Errorf("Unmarshal(%q) differs:\n%s", data, diff)
+		}
+	})
+	t.Run("key attribute", func(t *testing.T) {
+		m := &Codec{MapKeyAttr: "key"}
+		want := map[string]string{"a b": "1", "c": "2"}
+		buf, err := m.Marshal(want)
+		if err != nil {
+			t.Fatalf("Marshal(%v) failed with %v; want success", want, err)
+		}
+		var got map[string]string
+		if err = m.Unmarshal(buf, &got); err != nil {
+			t.Fatalf("Unmarshal(%q) failed with %v; want success", buf, err)
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("Unmarshal(%q) differs:\n%s", buf, diff)
+		}
+	})
+	t.Run("not text", func(t *testing.T) {
+		var got map[string]string
+		var fe *codec.FieldError
+		err := (&Codec{}).Unmarshal([]byte(`<root><a><b>1</b></a></root>`), &got)
+		if !errors.As(err, &fe) || fe.Field != "a" {
+			t.Errorf("Unmarshal() = %v; want *codec.FieldError of the field", err)
+		}
+	})
+}
+
+func TestCodec_EncoderDecoderMap(t *testing.T) {
+	m := &Codec{Header: true}
+	values := []map[string]any{{"a": "1"}, {"b": "2"}}
+
+	var buf bytes.Buffer
+	enc := m.NewEncoder(&buf)
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			t.Fatalf("Encode(%v) failed with %v; want success", v, err)
+		}
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("<?xml")); n != 1 {
+		t.Errorf("Encode() wrote %d XML declarations; want 1", n)
+	}
+	dec := m.NewDecoder(&buf)
+	for _, want := range values {
+		var got map[string]any
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("Decode() failed with %v; want success", err)
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("Decode() differs:\n%s", diff)
+		}
+	}
+}
@@ -0,0 +1,495 @@
+package xml
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/things-go/encoding/codec"
+	"github.com/things-go/encoding/internal/wellknown"
+)
+
+// encodeProto writes the message as the root element.
+func (c *Codec) encodeProto(e *xml.Encoder, m proto.Message) error {
+	rm := m.ProtoReflect()
+	if err := c.encodeMessage(e, c.rootStart(string(rm.Descriptor().Name())), rm); err != nil {
+		return err
+	}
+	return e.Flush()
+}
+
+func (c *Codec) encodeMessage(e *xml.Encoder, start xml.StartElement, m protoreflect.Message) error {
+	md := m.Descriptor()
+	if text, ok := c.formatWellKnownType(m); ok {
+		return e.EncodeElement(text, start)
+	}
+	if md.FullName() == "google.protobuf.Any" {
+		return c.encodeAny(e, start, m)
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		fieldStart := xml.StartElement{Name: xml.Name{Local: wellknown.FieldName(fd, c.UseProtoNames)}}
+		v := m.Get(fd)
+		var err error
+		switch {
+		case fd.IsList():
+			list := v.List()
+			for j := 0; j < list.Len() && err == nil; j++ {
+				err = c.encodeValue(e, fieldStart, fd, list.Get(j))
+			}
+		case fd.IsMap():
+			err = c.encodeMap(e, fieldStart, fd, v.Map())
+		default:
+			err = c.encodeValue(e, fieldStart, fd, v)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func (c *Codec) encodeMap(e *xml.Encoder, start xml.StartElement, fd protoreflect.FieldDescriptor, m protoreflect.Map) error {
+	keys := make([]protoreflect.MapKey, 0, m.Len())
+	m.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i].Interface(), keys[j].Interface()
+		switch a := a.(type) {
+		case string:
+			return a < b.(string)
+		case bool:
+			return !a && b.(bool)
+		case int32:
+			return a < b.(int32)
+		case int64:
+			return a < b.(int64)
+		case uint32:
+			return a < b.(uint32)
+		case uint64:
+			return a < b.(uint64)
+		}
+		return false
+	})
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	entry := xml.StartElement{Name: xml.Name{Local: "entry"}}
+	for _, k := range keys {
+		if err := e.EncodeToken(entry); err != nil {
+			return err
+		}
+		if err := c.encodeValue(e, xml.StartElement{Name: xml.Name{Local: "key"}}, fd.MapKey(), k.Value()); err != nil {
+			return err
+		}
+		if err := c.encodeValue(e, xml.StartElement{Name: xml.Name{Local: "value"}}, fd.MapValue(), m.Get(k)); err != nil {
+			return err
+		}
+		if err := e.EncodeToken(entry.End()); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeValue writes a singular value of the field as the element.
+func (c *Codec) encodeValue(e *xml.Encoder, start xml.StartElement, fd protoreflect.FieldDescriptor, v protoreflect.Value) error {
+	if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+		return c.encodeMessage(e, start, v.Message())
+	}
+	return e.EncodeElement(c.formatScalar(fd, v), start)
+}
+
+// formatScalar returns the text of the scalar value.
+func (c *Codec) formatScalar(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return strconv.FormatBool(v.Bool())
+	case protoreflect.EnumKind:
+		if !c.UseEnumNumbers {
+			if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+				return string(ev.Name())
+			}
+		}
+		return strconv.FormatInt(int64(v.Enum()), 10)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return strconv.FormatInt(v.Int(), 10)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return strconv.FormatUint(v.Uint(), 10)
+	case protoreflect.FloatKind:
+		return formatFloat(v.Float(), 32)
+	case protoreflect.DoubleKind:
+		return formatFloat(v.Float(), 64)
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	default:
+		return v.String()
+	}
+}
+
+func formatFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
+// formatWellKnownType returns the text of the well-known types which are encoded as text.
+func (c *Codec) formatWellKnownType(m protoreflect.Message) (string, bool) {
+	md := m.Descriptor()
+	switch name := md.FullName(); {
+	case name == "google.protobuf.Timestamp":
+		return wellknown.Timestamp(m).Format(time.RFC3339Nano), true
+	case name == "google.protobuf.Duration":
+		return wellknown.FormatDuration(m), true
+	case name == "google.protobuf.FieldMask":
+		return wellknown.FormatFieldMask(m), true
+	case wellknown.IsWrapper(name):
+		fd := md.Fields().ByNumber(1)
+		return c.formatScalar(fd, m.Get(fd)), true
+	}
+	return "", false
+}
+
+func (c *Codec) encodeAny(e *xml.Encoder, start xml.StartElement, m protoreflect.Message) error {
+	typeURL, inner, err := wellknown.UnpackAny(c.Resolver, m)
+	if err != nil {
+		return err
+	}
+	if inner == nil {
+		return e.EncodeElement("", start)
+	}
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "type"}, Value: typeURL})
+	return c.encodeMessage(e, start, inner)
+}
+
+// decodeProto reads the next root element into the message.
+func (c *Codec) decodeProto(d *xml.Decoder, m proto.Message) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			proto.Reset(m)
+			return c.decodeMessage(d, start, m.ProtoReflect(), "")
+		}
+	}
+}
+
+// decodeMessage reads the content of the start element into the message,
+// path is the dotted field path of the message for the errors.
+func (c *Codec) decodeMessage(d *xml.Decoder, start xml.StartElement, m protoreflect.Message, path string) error {
+	md := m.Descriptor()
+	if isTextWellKnownType(md.FullName()) {
+		text, err := c.readText(d, path)
+		if err != nil {
+			return err
+		}
+		return c.parseWellKnownType(m, text, path)
+	}
+	if md.FullName() == "google.protobuf.Any" {
+		return c.decodeAny(d, start, m, path)
+	}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			fd := md.Fields().ByJSONName(tok.Name.Local)
+			if fd == nil {
+				fd = md.Fields().ByTextName(tok.Name.Local)
+			}
+			fieldPath := wellknown.JoinPath(path, tok.Name.Local)
+			if fd == nil {
+				if !c.DiscardUnknown {
+					return &codec.FieldError{Field: fieldPath, Type: string(md.FullName()), Err: fmt.Errorf("xml: unknown field %q", tok.Name.Local)}
+				}
+				if err = d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			switch {
+			case fd.IsList():
+				list := m.Mutable(fd).List()
+				v, err := c.decodeValue(d, tok, fd, list.NewElement(), fieldPath)
+				if err != nil {
+					return err
+				}
+				list.Append(v)
+			case fd.IsMap():
+				if err = c.decodeMap(d, fd, m.Mutable(fd).Map(), fieldPath); err != nil {
+					return err
+				}
+			default:
+				var v protoreflect.Value
+				if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+					v = m.Mutable(fd)
+				}
+				if v, err = c.decodeValue(d, tok, fd, v, fieldPath); err != nil {
+					return err
+				}
+				m.Set(fd, v)
+			}
+		}
+	}
+}
+
+// decodeMap reads the "entry" elements of the map field.
+func (c *Codec) decodeMap(d *xml.Decoder, fd protoreflect.FieldDescriptor, m protoreflect.Map, path string) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			if tok.Name.Local != "entry" {
+				return &codec.FieldError{Field: path, Err: fmt.Errorf("xml: expect the map entry, got %q", tok.Name.Local)}
+			}
+			if err = c.decodeMapEntry(d, fd, m, path); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// decodeMapEntry reads an "entry" element into the map, the "key" element must precede the "value" element.
+func (c *Codec) decodeMapEntry(d *xml.Decoder, fd protoreflect.FieldDescriptor, m protoreflect.Map, path string) error {
+	var key protoreflect.MapKey
+	var hasKey, hasValue bool
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.EndElement:
+			if !hasKey {
+				return &codec.FieldError{Field: path, Err: errors.New("xml: the map entry without the key")}
+			}
+			if !hasValue {
+				m.Set(key, m.NewValue())
+			}
+			return nil
+		case xml.StartElement:
+			switch tok.Name.Local {
+			case "key":
+				v, err := c.decodeValue(d, tok, fd.MapKey(), protoreflect.Value{}, path)
+				if err != nil {
+					return err
+				}
+				key, hasKey = v.MapKey(), true
+			case "value":
+				if !hasKey {
+					return &codec.FieldError{Field: path, Err: errors.New("xml: the map value must follow the key")}
+				}
+				v, err := c.decodeValue(d, tok, fd.MapValue(), m.NewValue(), wellknown.JoinPath(path, key.String()))
+				if err != nil {
+					return err
+				}
+				m.Set(key, v)
+				hasValue = true
+			default:
+				return &codec.FieldError{Field: path, Err: fmt.Errorf("xml: unexpected element %q in the map entry", tok.Name.Local)}
+			}
+		}
+	}
+}
+
+// decodeValue reads a singular value of the field from the element,
+// v is the mutable message to read into for the message field.
+func (c *Codec) decodeValue(d *xml.Decoder, start xml.StartElement, fd protoreflect.FieldDescriptor, v protoreflect.Value, path string) (protoreflect.Value, error) {
+	if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+		return v, c.decodeMessage(d, start, v.Message(), path)
+	}
+	text, err := c.readText(d, path)
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	return c.parseScalar(fd, text, path)
+}
+
+// readText reads the text content of the element until its end.
+func (c *Codec) readText(d *xml.Decoder, path string) (string, error) {
+	var b strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return "", err
+		}
+		switch tok := tok.(type) {
+		case xml.CharData:
+			b.Write(tok)
+		case xml.EndElement:
+			return b.String(), nil
+		case xml.StartElement:
+			return "", &codec.FieldError{Field: path, Err: fmt.Errorf("xml: unexpected element %q in the text", tok.Name.Local)}
+		}
+	}
+}
+
+// parseScalar parses the text of the scalar value.
+func (c *Codec) parseScalar(fd protoreflect.FieldDescriptor, text, path string) (protoreflect.Value, error) {
+	kind := fd.Kind()
+	if kind != protoreflect.StringKind {
+		text = strings.TrimSpace(text)
+	}
+	var v protoreflect.Value
+	var err error
+	switch kind {
+	case protoreflect.StringKind:
+		v = protoreflect.ValueOfString(text)
+	case protoreflect.BoolKind:
+		var b bool
+		b, err = strconv.ParseBool(text)
+		v = protoreflect.ValueOfBool(b)
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(text)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		var n int64
+		n, err = strconv.ParseInt(text, 10, 32)
+		v = protoreflect.ValueOfEnum(protoreflect.EnumNumber(n))
+		if err != nil {
+			return v, &codec.FieldError{Field: path, Value: text, Type: string(fd.Enum().FullName()), Err: err}
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		var n int64
+		n, err = strconv.ParseInt(text, 10, 32)
+		v = protoreflect.ValueOfInt32(int32(n))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		var n int64
+		n, err = strconv.ParseInt(text, 10, 64)
+		v = protoreflect.ValueOfInt64(n)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		var n uint64
+		n, err = strconv.ParseUint(text, 10, 32)
+		v = protoreflect.ValueOfUint32(uint32(n))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		var n uint64
+		n, err = strconv.ParseUint(text, 10, 64)
+		v = protoreflect.ValueOfUint64(n)
+	case protoreflect.FloatKind:
+		var f float64
+		f, err = parseFloat(text, 32)
+		v = protoreflect.ValueOfFloat32(float32(f))
+	case protoreflect.DoubleKind:
+		var f float64
+		f, err = parseFloat(text, 64)
+		v = protoreflect.ValueOfFloat64(f)
+	case protoreflect.BytesKind:
+		var b []byte
+		b, err = base64.StdEncoding.DecodeString(text)
+		v = protoreflect.ValueOfBytes(b)
+	default:
+		err = fmt.Errorf("xml: unsupported kind %v", kind)
+	}
+	if err != nil {
+		return v, &codec.FieldError{Field: path, Value: text, Type: kind.String(), Err: err}
+	}
+	return v, nil
+}
+
+func parseFloat(text string, bitSize int) (float64, error) {
+	switch text {
+	case "NaN":
+		return math.NaN(), nil
+	case "Infinity":
+		return math.Inf(1), nil
+	case "-Infinity":
+		return math.Inf(-1), nil
+	}
+	return strconv.ParseFloat(text, bitSize)
+}
+
+// isTextWellKnownType reports whether the well-known type is encoded as text.
+func isTextWellKnownType(name protoreflect.FullName) bool {
+	switch name {
+	case "google.protobuf.Timestamp", "google.protobuf.Duration", "google.protobuf.FieldMask":
+		return true
+	}
+	return wellknown.IsWrapper(name)
+}
+
+// parseWellKnownType parses the text of the well-known type into the message.
+func (c *Codec) parseWellKnownType(m protoreflect.Message, text, path string) error {
+	md := m.Descriptor()
+	text = strings.TrimSpace(text)
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		t, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return &codec.FieldError{Field: path, Value: text, Type: string(md.FullName()), Err: err}
+		}
+		wellknown.SetTimestamp(m, t)
+	case "google.protobuf.Duration":
+		if err := wellknown.SetDuration(m, text); err != nil {
+			return &codec.FieldError{Field: path, Value: text, Type: string(md.FullName()), Err: err}
+		}
+	case "google.protobuf.FieldMask":
+		wellknown.SetFieldMask(m, text)
+	default:
+		fd := md.Fields().ByNumber(1)
+		v, err := c.parseScalar(fd, text, path)
+		if err != nil {
+			return err
+		}
+		m.Set(fd, v)
+	}
+	return nil
+}
+
+// decodeAny reads the Any element, the fields are read into the message of the "type" attribute.
+func (c *Codec) decodeAny(d *xml.Decoder, start xml.StartElement, m protoreflect.Message, path string) error {
+	var typeURL string
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "type" {
+			typeURL = attr.Value
+		}
+	}
+	if typeURL == "" {
+		_, err := c.readText(d, path)
+		return err
+	}
+	mt, err := wellknown.Resolver(c.Resolver).FindMessageByURL(typeURL)
+	if err != nil {
+		return &codec.FieldError{Field: path, Value: typeURL, Type: "google.protobuf.Any", Err: err}
+	}
+	inner := mt.New()
+	if err = c.decodeMessage(d, start, inner, path); err != nil {
+		return err
+	}
+	return wellknown.PackAny(m, typeURL, inner)
+}
@@ -0,0 +1,184 @@
+package xml
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/things-go/encoding/codec"
+	"github.com/things-go/encoding/testdata/examplepb"
+)
+
+var protoMessage = &examplepb.ABitOfEverything{
+	SingleNested: &examplepb.ABitOfEverything_Nested{Name: "nested", Ok: examplepb.ABitOfEverything_Nested_TRUE},
+	Uuid:         "6EC2446F-7E89-4127-B3E6-5C05E6BECBA7",
+	Nested: []*examplepb.ABitOfEverything_Nested{
+		{Name: "foo", Amount: 12345},
+		{Name: "<bar>"},
+	},
+	FloatValue:          float32(math.Inf(1)),
+	DoubleValue:         1.5,
+	Int64Value:          -1,
+	Uint64Value:         0xFFFFFFFFFFFFFFFF,
+	BoolValue:           true,
+	BytesValue:          []byte("bytes"),
+	EnumValue:           examplepb.NumericEnum_ONE,
+	RepeatedStringValue: []string{"a", "b"},
+	OneofValue:          &examplepb.ABitOfEverything_OneofString{OneofString: "bar"},
+	MapValue: map[string]examplepb.NumericEnum{
+		"b": examplepb.NumericEnum_ZERO,
+		"a": examplepb.NumericEnum_ONE,
+	},
+	MappedNestedValue: map[string]*examplepb.ABitOfEverything_Nested{
+		"x": {Name: "x"},
+	},
+	TimestampValue: &timestamppb.Timestamp{Seconds: 1704164645, Nanos: 500000000},
+}
+
+func TestCodec_Proto(t *testing.T) {
+	m := &Codec{}
+	buf, err := m.Marshal(protoMessage)
+	if err != nil {
+		t.Fatalf("m.Marshal(%v) failed with %v; want success", protoMessage, err)
+	}
+	got := string(buf)
+	for _, want := range []string{
+		`<ABitOfEverything>`,
+		`<singleNested><name>nested</name><ok>TRUE</ok></singleNested>`,
+		`<nested><name>foo</name><amount>12345</amount></nested><nested><name>&lt;bar&gt;</name></nested>`,
+		`<floatValue>Infinity</floatValue>`,
+		`<bytesValue>Ynl0ZXM=</bytesValue>`,
+		`<enumValue>ONE</enumValue>`,
+		`<repeatedStringValue>a</repeatedStringValue><repeatedStringValue>b</repeatedStringValue>`,
+		`<mapValue><entry><key>a</key><value>ONE</value></entry><entry><key>b</key><value>ZERO</value></entry></mapValue>`,
+		`<mappedNestedValue><entry><key>x</key><value><name>x</name></value></entry></mappedNestedValue>`,
+		`<timestampValue>2024-01-02T03:04:05.5Z</timestampValue>`,
+		`<oneofString>bar</oneofString>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("m.Marshal(%v) = %q; want contains %q", protoMessage, got, want)
+		}
+	}
+
+	unmarshalled := &examplepb.ABitOfEverything{}
+	if err = m.Unmarshal(buf, unmarshalled); err != nil {
+		t.Fatalf("m.Unmarshal(%q) failed with %v; want success", buf, err)
+	}
+	if diff := cmp.Diff(unmarshalled, protoMessage, protocmp.Transform()); diff != "" {
+		t.Errorf("m.Unmarshal(%q) differs:\n%s", buf, diff)
+	}
+
+	var w bytes.Buffer
+	if err = m.NewEncoder(&w).Encode(protoMessage); err != nil {
+		t.Fatalf("Encode(%v) failed with %v; want success", protoMessage, err)
+	}
+	decoded := &examplepb.ABitOfEverything{}
+	if err = m.NewDecoder(&w).Decode(decoded); err != nil {
+		t.Fatalf("Decode() failed with %v; want success", err)
+	}
+	if diff := cmp.Diff(decoded, protoMessage, protocmp.Transform()); diff != "" {
+		t.Errorf("Decode() differs:\n%s", diff)
+	}
+}
+
+func TestCodec_ProtoOptions(t *testing.T) {
+	m := &Codec{RootName: "item", UseProtoNames: true, UseEnumNumbers: true}
+	msg := &examplepb.ABitOfEverything{EnumValue: examplepb.NumericEnum_ONE, StringValue: "s"}
+	buf, err := m.Marshal(msg)
+	if err != nil {
+		t.Fatalf("m.Marshal(%v) failed with %v; want success", msg, err)
+	}
+	if got, want := string(buf), `<item><string_value>s</string_value><enum_value>1</enum_value></item>`; got != want {
+		t.Errorf("m.Marshal(%v) = %q; want %q", msg, got, want)
+	}
+	got := &examplepb.ABitOfEverything{}
+	if err = m.Unmarshal(buf, got); err != nil {
+		t.Fatalf("m.Unmarshal(%q) failed with %v; want success", buf, err)
+	}
+	if diff := cmp.Diff(got, msg, protocmp.Transform()); diff != "" {
+		t.Errorf("m.Unmarshal(%q) differs:\n%s", buf, diff)
+	}
+
+	data := `<x><uuid>1</uuid><unknown><a>1</a></unknown></x>`
+	var fe *codec.FieldError
+	if err = (&Codec{}).Unmarshal([]byte(data), &examplepb.ABitOfEverything{}); !errors.As(err, &fe) || fe.Field != "unknown" {
+		t.Errorf("m.Unmarshal(%q) = %v; want *codec.FieldError of the unknown field", data, err)
+	}
+	got = &examplepb.ABitOfEverything{}
+	if err = (&Codec{DiscardUnknown: true}).Unmarshal([]byte(data), got); err != nil || got.GetUuid() != "1" {
+		t.Errorf("m.Unmarshal(%q) = %v, %v; want the unknown field discarded", data, got, err)
+	}
+}
+
+func TestCodec_ProtoWellKnownTypes(t *testing.T) {
+	inner, err := anypb.New(&examplepb.SimpleMessage{Id: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		msg  proto.Message
+		want string
+	}{
+		{&timestamppb.Timestamp{Seconds: 1}, `<Timestamp>1970-01-01T00:00:01Z</Timestamp>`},
+		{&durationpb.Duration{Seconds: -1, Nanos: -500000000}, `<Duration>-1.5s</Duration>`},
+		{&fieldmaskpb.FieldMask{Paths: []string{"a.b", "c"}}, `<FieldMask>a.b,c</FieldMask>`},
+		{wrapperspb.Int64(7), `<Int64Value>7</Int64Value>`},
+		{wrapperspb.String("s"), `<StringValue>s</StringValue>`},
+		{inner, `<Any type="type.googleapis.com/dyn.encoding.testdata.examplepb.SimpleMessage"><id>1</id></Any>`},
+	}
+	m := &Codec{}
+	for _, tt := range tests {
+		buf, err := m.Marshal(tt.msg)
+		if err != nil {
+			t.Fatalf("m.Marshal(%v) failed with %v; want success", tt.msg, err)
+		}
+		if got := string(buf); got != tt.want {
+			t.Errorf("m.Marshal(%v) = %q; want %q", tt.msg, got, tt.want)
+		}
+		got := tt.msg.ProtoReflect().New().Interface()
+		if err = m.Unmarshal(buf, got); err != nil {
+			t.Fatalf("m.Unmarshal(%q) failed with %v; want success", buf, err)
+		}
+		if diff := cmp.Diff(got, tt.msg, protocmp.Transform()); diff != "" {
+			t.Errorf("m.Unmarshal(%q) differs:\n%s", buf, diff)
+		}
+	}
+}
+
+func TestCodec_ProtoFieldError(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want codec.FieldError
+	}{
+		{"invalid value", `<x><int64Value>abc</int64Value></x>`, codec.FieldError{Field: "int64Value", Value: "abc", Type: "int64"}},
+		{"nested", `<x><nested><amount>-1</amount></nested></x>`, codec.FieldError{Field: "nested.amount", Value: "-1", Type: "uint32"}},
+		{"map value", `<x><mappedNestedValue><entry><key>k</key><value><amount>x</amount></value></entry></mappedNestedValue></x>`, codec.FieldError{Field: "mappedNestedValue.k.amount", Value: "x", Type: "uint32"}},
+		{"enum", `<x><enumValue>TWO</enumValue></x>`, codec.FieldError{Field: "enumValue", Value: "TWO", Type: "dyn.encoding.testdata.examplepb.NumericEnum"}},
+		{"timestamp", `<x><timestampValue>yesterday</timestampValue></x>`, codec.FieldError{Field: "timestampValue", Value: "yesterday", Type: "google.protobuf.Timestamp"}},
+	}
+	m := &Codec{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fe *codec.FieldError
+			err := m.Unmarshal([]byte(tt.data), &examplepb.ABitOfEverything{})
+			if !errors.As(err, &fe) {
+				t.Fatalf("error = %v, want *codec.FieldError", err)
+			}
+			if fe.Field != tt.want.Field || fe.Value != tt.want.Value || fe.Type != tt.want.Type {
+				t.Errorf("FieldError = %+v, want %+v", fe, tt.want)
+			}
+		})
+	}
+}
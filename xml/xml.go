@@ -1,28 +1,139 @@
 package xml
 
 import (
+	"bytes"
 	"encoding/xml"
+	"errors"
 	"io"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
 
 	"github.com/things-go/encoding/codec"
 )
 
-// Codec is a Codec implementation with xml.
-type Codec struct{}
+// Codec is a Codec implementation with xml, see the package doc for the mapping
+// of the proto messages, maps and slices.
+// NOTE: the NewEncoder returns an *EncoderWrapper, not an *xml.Encoder,
+// and the NewDecoder returns a DecoderWrapper of *xml.Decoder.
+type Codec struct {
+	// RootName is the root element name of the proto messages, maps and slices,
+	// default the message name or "root".
+	RootName string
+	// MapKeyAttr writes the map entries as the "entry" elements with the key in
+	// the attribute of the name, e.g. <entry key="a b">, for the keys which are not
+	// valid element names.
+	MapKeyAttr string
+	// Indent is the indentation of the elements, no indentation if empty.
+	Indent string
+	// Header writes the XML declaration xml.Header before the first value.
+	Header bool
+	// Namespaces are the namespace declarations of the root element of the proto messages,
+	// maps and slices, keyed by the prefix, the empty prefix is the default namespace.
+	Namespaces map[string]string
+	// UseProtoNames uses the proto field names instead of the JSON names as the element names.
+	UseProtoNames bool
+	// UseEnumNumbers writes the enums as the numbers instead of the names.
+	UseEnumNumbers bool
+	// DiscardUnknown ignores the unknown elements of the proto messages,
+	// otherwise they are reported as *codec.FieldError.
+	DiscardUnknown bool
+	// Resolver resolves the message types of Any, default protoregistry.GlobalTypes.
+	Resolver protoregistry.MessageTypeResolver
+}
 
 // ContentType always Returns "application/xml; charset=utf-8".
 func (*Codec) ContentType(_ any) string {
 	return "application/xml; charset=utf-8"
 }
-func (*Codec) Marshal(v any) ([]byte, error) {
-	return xml.Marshal(v)
+func (c *Codec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+func (c *Codec) Unmarshal(data []byte, v any) error {
+	return c.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+func (c *Codec) NewEncoder(w io.Writer) codec.Encoder {
+	e := xml.NewEncoder(w)
+	if c.Indent != "" {
+		e.Indent("", c.Indent)
+	}
+	return &EncoderWrapper{Encoder: e, codec: c, w: w}
+}
+func (c *Codec) NewDecoder(r io.Reader) codec.Decoder {
+	return DecoderWrapper{Decoder: xml.NewDecoder(r), codec: c}
+}
+
+// EncoderWrapper is a wrapper around a *xml.Encoder that adds
+// support for protos, maps and slices to the Encode method.
+type EncoderWrapper struct {
+	*xml.Encoder
+	codec      *Codec
+	w          io.Writer
+	headerDone bool
+}
+
+// Encode wraps the embedded encoder's Encode method to support protos, maps and slices.
+func (e *EncoderWrapper) Encode(v any) error {
+	if e.codec.Header && !e.headerDone {
+		if err := e.Flush(); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(e.w, xml.Header); err != nil {
+			return err
+		}
+		e.headerDone = true
+	}
+	if m, ok := v.(proto.Message); ok {
+		return e.codec.encodeProto(e.Encoder, m)
+	}
+	if isGeneric(v) {
+		return e.codec.encodeGeneric(e.Encoder, v)
+	}
+	return e.Encoder.Encode(v)
 }
-func (*Codec) Unmarshal(data []byte, v any) error {
-	return xml.Unmarshal(data, v)
+
+// DecoderWrapper is a wrapper around a *xml.Decoder that adds
+// support for protos to the Decode method, and reports
+// the decode failures as *codec.FieldError.
+type DecoderWrapper struct {
+	*xml.Decoder
+	codec *Codec
 }
-func (*Codec) NewEncoder(w io.Writer) codec.Encoder {
-	return xml.NewEncoder(w)
+
+// Decode wraps the embedded decoder's Decode method.
+func (d DecoderWrapper) Decode(v any) error {
+	if m, ok := v.(proto.Message); ok {
+		return wrapError(d.codec.decodeProto(d.Decoder, m))
+	}
+	if rv, ok := genericTarget(v); ok {
+		return wrapError(d.codec.decodeGeneric(d.Decoder, rv))
+	}
+	return wrapError(d.Decoder.Decode(v))
 }
-func (*Codec) NewDecoder(r io.Reader) codec.Decoder {
-	return xml.NewDecoder(r)
+
+// wrapError converts the decode failures into *codec.FieldError,
+// other errors (e.g. the reader's error) are returned as is.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var fieldErr *codec.FieldError
+	var syntaxErr *xml.SyntaxError
+	var unmarshalErr xml.UnmarshalError
+	var numErr *strconv.NumError
+	switch {
+	case errors.As(err, &fieldErr):
+		return err
+	case errors.As(err, &syntaxErr), errors.As(err, &unmarshalErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return &codec.FieldError{Err: err}
+	case errors.As(err, &numErr):
+		return &codec.FieldError{Value: numErr.Num, Type: strings.ToLower(strings.TrimPrefix(numErr.Func, "Parse")), Err: err}
+	}
+	return err
 }
@@ -2,9 +2,12 @@ package xml
 
 import (
 	"bytes"
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/things-go/encoding/codec"
 )
 
 type Plain struct {
@@ -151,3 +154,34 @@ func TestCodec_NilUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestCodec_DecodeFieldError(t *testing.T) {
+	type value struct {
+		N int `xml:"n"`
+	}
+	tests := []struct {
+		name string
+		data string
+		want codec.FieldError
+	}{
+		{"invalid value", `<value><n>abc</n></value>`, codec.FieldError{Value: "abc", Type: "int"}},
+		{"syntax", `<value><n>abc</value>`, codec.FieldError{}},
+	}
+	m := Codec{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, err := range []error{
+				m.Unmarshal([]byte(tt.data), &value{}),
+				m.NewDecoder(strings.NewReader(tt.data)).Decode(&value{}),
+			} {
+				var fe *codec.FieldError
+				if !errors.As(err, &fe) {
+					t.Fatalf("error = %v, want *codec.FieldError", err)
+				}
+				if fe.Value != tt.want.Value || fe.Type != tt.want.Type {
+					t.Errorf("FieldError = %+v, want %+v", fe, tt.want)
+				}
+			}
+		})
+	}
+}
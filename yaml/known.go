@@ -0,0 +1,219 @@
+package yaml
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/things-go/encoding/codec"
+)
+
+// yaml reports a type error like "line 1: cannot unmarshal !!str `abc` into int".
+var lineRegexp = regexp.MustCompile(`^line (\d+): `)
+
+// decodeKnownFields decodes the node into v, the keys which match no struct fields are
+// found by walking the node against the type of v, and reported as codec.FieldErrors with
+// the dotted field paths and the lines, together with the decode failures in line order.
+func decodeKnownFields(node *yaml.Node, v any) error {
+	if node.Kind == 0 { // an empty document.
+		return nil
+	}
+	c := &knownFieldsChecker{aliases: make(map[*yaml.Node]bool)}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer {
+		c.walk(node, rv.Type().Elem(), "")
+	}
+	if err := node.Decode(v); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return wrapError(err)
+		}
+		for _, e := range typeErr.Errors {
+			line := 0
+			if m := lineRegexp.FindStringSubmatch(e); m != nil {
+				line, _ = strconv.Atoi(m[1])
+			}
+			c.add(line, typeFieldError(e))
+		}
+	}
+	sort.SliceStable(c.errs, func(i, j int) bool { return c.errs[i].line < c.errs[j].line })
+	errs := make(codec.FieldErrors, 0, len(c.errs))
+	for _, e := range c.errs {
+		errs = append(errs, e.err)
+	}
+	return errs.Err()
+}
+
+// lineError is a decode failure at the line.
+type lineError struct {
+	line int
+	err  *codec.FieldError
+}
+
+// knownFieldsChecker walks the node against the target type like yaml.v3 decodes,
+// and records the keys which match no struct fields.
+type knownFieldsChecker struct {
+	errs []lineError
+	// aliases are the anchored nodes being walked, so a recursive alias stops.
+	aliases map[*yaml.Node]bool
+}
+
+func (c *knownFieldsChecker) add(line int, err *codec.FieldError) {
+	c.errs = append(c.errs, lineError{line: line, err: err})
+}
+
+var (
+	nodeType          = reflect.TypeOf(yaml.Node{})
+	unmarshalerType   = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	obsoleteUnmarType = reflect.TypeOf((*obsoleteUnmarshaler)(nil)).Elem()
+)
+
+// obsoleteUnmarshaler is the yaml.v2 Unmarshaler, which yaml.v3 still supports.
+type obsoleteUnmarshaler interface {
+	UnmarshalYAML(unmarshal func(any) error) error
+}
+
+// walk walks the node decoded into the type t, path is the dotted field path of the node.
+// The values decoded by themselves, e.g. any, yaml.Node and the Unmarshalers, are not walked.
+func (c *knownFieldsChecker) walk(n *yaml.Node, t reflect.Type, path string) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 1 {
+			c.walk(n.Content[0], t, path)
+		}
+		return
+	case yaml.AliasNode:
+		if n.Alias != nil && !c.aliases[n.Alias] {
+			c.aliases[n.Alias] = true
+			c.walk(n.Alias, t, path)
+			delete(c.aliases, n.Alias)
+		}
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nodeType || t.Kind() == reflect.Interface ||
+		reflect.PointerTo(t).Implements(unmarshalerType) || reflect.PointerTo(t).Implements(obsoleteUnmarType) {
+		return
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		switch t.Kind() {
+		case reflect.Struct:
+			c.walkStruct(n, t, structFieldsOf(t), path)
+		case reflect.Map:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if isMerge(n.Content[i]) {
+					c.walkMerge(n.Content[i+1], t, path)
+					continue
+				}
+				c.walk(n.Content[i+1], t.Elem(), joinPath(path, n.Content[i].Value))
+			}
+		}
+	case yaml.SequenceNode:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, item := range n.Content {
+				c.walk(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+}
+
+func (c *knownFieldsChecker) walkStruct(n *yaml.Node, t reflect.Type, fields *structFields, path string) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if isMerge(key) {
+			c.walkMerge(value, t, path)
+			continue
+		}
+		field := joinPath(path, key.Value)
+		switch ft, ok := fields.byName[key.Value]; {
+		case ok:
+			c.walk(value, ft, field)
+		case fields.inlineMap != nil:
+			c.walk(value, fields.inlineMap.Elem(), field)
+		default:
+			c.add(key.Line, &codec.FieldError{
+				Field: field,
+				Type:  t.String(),
+				Err:   fmt.Errorf("line %d: field %s not found in type %s", key.Line, key.Value, t),
+			})
+		}
+	}
+}
+
+// walkMerge walks the value of a merge key "<<", a mapping, an alias of it, or a sequence of them,
+// whose keys are merged into the mapping of the type t.
+func (c *knownFieldsChecker) walkMerge(n *yaml.Node, t reflect.Type, path string) {
+	if n.Kind == yaml.SequenceNode {
+		for _, item := range n.Content {
+			c.walk(item, t, path)
+		}
+		return
+	}
+	c.walk(n, t, path)
+}
+
+func isMerge(key *yaml.Node) bool {
+	return key.Kind == yaml.ScalarNode && key.ShortTag() == "!!merge"
+}
+
+// structFields are the fields of a struct type keyed by the yaml names, the same as yaml.v3.
+type structFields struct {
+	byName map[string]reflect.Type
+	// inlineMap is the map type of the ",inline" map field which accepts the other keys.
+	inlineMap reflect.Type
+}
+
+func structFieldsOf(t reflect.Type) *structFields {
+	fields := &structFields{byName: make(map[string]reflect.Type)}
+	fields.add(t)
+	return fields
+}
+
+func (f *structFields) add(t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() && !sf.Anonymous {
+			continue
+		}
+		tag := sf.Tag.Get("yaml")
+		if tag == "" && !strings.Contains(string(sf.Tag), ":") {
+			tag = string(sf.Tag)
+		}
+		if tag == "-" {
+			continue
+		}
+		name, flags, _ := strings.Cut(tag, ",")
+		if strings.Contains(","+flags+",", ",inline,") {
+			ft := sf.Type
+			switch {
+			case ft.Kind() == reflect.Map:
+				f.inlineMap = ft
+			case ft.Kind() == reflect.Struct:
+				f.add(ft)
+			case ft.Kind() == reflect.Pointer && ft.Elem().Kind() == reflect.Struct:
+				f.add(ft.Elem())
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+		f.byName[name] = sf.Type
+	}
+}
+
+// joinPath joins the field path with the key.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,511 @@
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+
+	"github.com/things-go/encoding/codec"
+)
+
+// The proto messages are round-tripped through the protojson semantics, i.e. the JSON field names,
+// the well-known types encodings, Any with "@type", the enums as strings, and written and read as
+// the idiomatic block style YAML instead of the JSON:
+//
+//	id: "1"
+//	createTime: "2024-01-02T03:04:05Z"
+//	status: STATUS_ACTIVE
+//	labels:
+//	  env: prod
+//
+// When reading, the plain scalars are converted by the field types, e.g. `id: 1` and
+// `createTime: 2024-01-02T03:04:05Z`, and the anchors, aliases and merge keys are resolved.
+// A slice or a map of messages is supported like jsonpb.Codec does.
+
+var typeProtoMessage = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+// isProtoTarget reports whether v is a proto message, or a pointer to a message,
+// a slice of messages or a map of messages, which are encoded with the protojson semantics.
+func isProtoTarget(v any) bool {
+	if _, ok := v.(proto.Message); ok {
+		return true
+	}
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return false
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return t.Elem().Implements(typeProtoMessage)
+	}
+	return false
+}
+
+// protoToNode converts the proto target into the block style yaml.Node.
+func protoToNode(opts protojson.MarshalOptions, v any) (*yaml.Node, error) {
+	if m, ok := v.(proto.Message); ok {
+		b, err := opts.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		var doc yaml.Node
+		if err = yaml.Unmarshal(b, &doc); err != nil {
+			return nil, err
+		}
+		node := doc.Content[0]
+		blockStyle(node)
+		return node, nil
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for i := 0; i < rv.Len(); i++ {
+			item, err := protoToNode(opts, rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, item)
+		}
+		return node, nil
+	case reflect.Map:
+		keys := make([]string, 0, rv.Len())
+		values := make(map[string]reflect.Value, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := fmt.Sprintf("%v", iter.Key().Interface())
+			keys = append(keys, key)
+			values[key] = iter.Value()
+		}
+		sort.Strings(keys)
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range keys {
+			value, err := protoToNode(opts, values[k].Interface())
+			if err != nil {
+				return nil, err
+			}
+			key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}
+			node.Content = append(node.Content, key, value)
+		}
+		return node, nil
+	default:
+		return nil, fmt.Errorf("unable to marshal %T as proto", v)
+	}
+}
+
+// blockStyle resets the JSON flow styles and quotes of the node,
+// the strings which look like other types are still quoted by the encoder.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, n := range node.Content {
+		blockStyle(n)
+	}
+}
+
+// decodeProtoNode decodes the node into the proto target v.
+func decodeProtoNode(opts protojson.UnmarshalOptions, node *yaml.Node, v any) error {
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		node = node.Content[0]
+	}
+	if m, ok := v.(proto.Message); ok {
+		var buf bytes.Buffer
+		if err := nodeToJSON(&buf, node, m.ProtoReflect().Descriptor()); err != nil {
+			return &codec.FieldError{Type: string(m.ProtoReflect().Descriptor().FullName()), Err: err}
+		}
+		return wrapProtoError(opts.Unmarshal(buf.Bytes(), m), m)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("%T is not a pointer", v)
+	}
+	rv = rv.Elem()
+	for rv.Kind() == reflect.Ptr {
+		if rv.Type().Implements(typeProtoMessage) {
+			if rv.IsNil() {
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			return decodeProtoNode(opts, node, rv.Interface())
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.ShortTag() == "!!null" {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return &codec.FieldError{Type: rv.Type().String(), Err: fmt.Errorf("yaml: line %d: expect a sequence", node.Line)}
+		}
+		sl := reflect.MakeSlice(rv.Type(), len(node.Content), len(node.Content))
+		for i, item := range node.Content {
+			if err := decodeProtoNode(opts, item, sl.Index(i).Addr().Interface()); err != nil {
+				return withFieldPrefix(err, fmt.Sprintf("[%d]", i))
+			}
+		}
+		rv.Set(sl)
+		return nil
+	case reflect.Map:
+		conv, ok := convFromType[rv.Type().Key().Kind()]
+		if !ok {
+			return fmt.Errorf("unsupported type of map field key: %v", rv.Type().Key())
+		}
+		if node.Kind != yaml.MappingNode {
+			return &codec.FieldError{Type: rv.Type().String(), Err: fmt.Errorf("yaml: line %d: expect a mapping", node.Line)}
+		}
+		pairs, err := mappingPairs(node)
+		if err != nil {
+			return &codec.FieldError{Type: rv.Type().String(), Err: err}
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		for _, pair := range pairs {
+			k := pair[0].Value
+			result := conv.Call([]reflect.Value{reflect.ValueOf(k)})
+			if err := result[1].Interface(); err != nil {
+				return &codec.FieldError{Field: k, Value: k, Type: rv.Type().Key().String(), Err: err.(error)}
+			}
+			item := reflect.New(rv.Type().Elem())
+			if err := decodeProtoNode(opts, pair[1], item.Interface()); err != nil {
+				return withFieldPrefix(err, k)
+			}
+			rv.SetMapIndex(result[0].Convert(rv.Type().Key()), item.Elem())
+		}
+		return nil
+	default:
+		return fmt.Errorf("unable to unmarshal %T as proto", v)
+	}
+}
+
+// withFieldPrefix prefixes the Field of the *codec.FieldError.
+func withFieldPrefix(err error, prefix string) error {
+	var fe *codec.FieldError
+	if !errors.As(err, &fe) {
+		return err
+	}
+	switch {
+	case fe.Field == "":
+		fe.Field = prefix
+	case strings.HasPrefix(fe.Field, "["):
+		fe.Field = prefix + fe.Field
+	default:
+		fe.Field = prefix + "." + fe.Field
+	}
+	return err
+}
+
+// nodeToJSON writes the node as JSON for protojson, the merge keys and aliases are resolved.
+// If md is not nil, the fields of the message are written by the field types, e.g. a plain
+// scalar `id: 1` is a string for the string field, otherwise the scalars are written by the
+// YAML resolved types.
+func nodeToJSON(buf *bytes.Buffer, node *yaml.Node, md protoreflect.MessageDescriptor) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return nodeToJSON(buf, node.Content[0], md)
+	case yaml.AliasNode:
+		return nodeToJSON(buf, node.Alias, md)
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := nodeToJSON(buf, item, nil); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case yaml.MappingNode:
+		pairs, err := mappingPairs(node)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('{')
+		for i, pair := range pairs {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(pair[0].Value)
+			buf.Write(key)
+			buf.WriteByte(':')
+			var fd protoreflect.FieldDescriptor
+			if md != nil {
+				if fd = md.Fields().ByJSONName(pair[0].Value); fd == nil {
+					fd = md.Fields().ByTextName(pair[0].Value)
+				}
+			}
+			if fd != nil {
+				err = fieldToJSON(buf, pair[1], fd)
+			} else {
+				err = nodeToJSON(buf, pair[1], nil)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case yaml.ScalarNode:
+		return scalarToJSON(buf, node)
+	default:
+		return fmt.Errorf("yaml: line %d: unexpected node kind %v", node.Line, node.Kind)
+	}
+}
+
+// fieldToJSON writes the node of the field as JSON by the field type.
+func fieldToJSON(buf *bytes.Buffer, node *yaml.Node, fd protoreflect.FieldDescriptor) error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	switch {
+	case fd.IsList() && node.Kind == yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := valueToJSON(buf, item, fd); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case fd.IsMap() && node.Kind == yaml.MappingNode:
+		pairs, err := mappingPairs(node)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('{')
+		for i, pair := range pairs {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(pair[0].Value)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err = valueToJSON(buf, pair[1], fd.MapValue()); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	default:
+		return valueToJSON(buf, node, fd)
+	}
+}
+
+// valueToJSON writes the node of a singular value of the field as JSON by the field type,
+// the plain scalars are strings for the string, bytes and enum (except the integers) fields,
+// e.g. `id: 1` and `status: TRUE`.
+func valueToJSON(buf *bytes.Buffer, node *yaml.Node, fd protoreflect.FieldDescriptor) error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind != yaml.ScalarNode {
+		if fd.Kind() == protoreflect.MessageKind && !isWellKnownType(fd.Message()) {
+			return nodeToJSON(buf, node, fd.Message())
+		}
+		return nodeToJSON(buf, node, nil)
+	}
+	tag := node.ShortTag()
+	if tag == "!!null" {
+		buf.WriteString("null")
+		return nil
+	}
+	asString := false
+	switch fd.Kind() {
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		asString = true
+	case protoreflect.EnumKind:
+		asString = tag != "!!int"
+	case protoreflect.MessageKind:
+		switch fd.Message().FullName() {
+		case "google.protobuf.StringValue", "google.protobuf.BytesValue":
+			asString = true
+		}
+	}
+	if !asString {
+		return scalarToJSON(buf, node)
+	}
+	value := node.Value
+	if tag == "!!binary" {
+		value = strings.Join(strings.Fields(value), "")
+	}
+	b, _ := json.Marshal(value)
+	buf.Write(b)
+	return nil
+}
+
+// isWellKnownType reports whether the message is a well-known type,
+// which has a special JSON encoding.
+func isWellKnownType(md protoreflect.MessageDescriptor) bool {
+	return md.ParentFile().Package() == "google.protobuf"
+}
+
+// mappingPairs returns the key-value pairs of the mapping, the merged pairs ("<<") are
+// added if the key is not set explicitly.
+func mappingPairs(node *yaml.Node) ([][2]*yaml.Node, error) {
+	var pairs [][2]*yaml.Node
+	seen := make(map[string]bool)
+	var merges []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Kind == yaml.AliasNode {
+			key = key.Alias
+		}
+		if key.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("yaml: line %d: invalid map key", key.Line)
+		}
+		if key.ShortTag() == "!!merge" {
+			merges = append(merges, value)
+			continue
+		}
+		if !seen[key.Value] {
+			seen[key.Value] = true
+			pairs = append(pairs, [2]*yaml.Node{key, value})
+		}
+	}
+	for _, merge := range merges {
+		if merge.Kind == yaml.AliasNode {
+			merge = merge.Alias
+		}
+		sources := []*yaml.Node{merge}
+		if merge.Kind == yaml.SequenceNode {
+			sources = merge.Content
+		}
+		for _, src := range sources {
+			if src.Kind == yaml.AliasNode {
+				src = src.Alias
+			}
+			if src.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("yaml: line %d: map merge requires map or sequence of maps as the value", src.Line)
+			}
+			merged, err := mappingPairs(src)
+			if err != nil {
+				return nil, err
+			}
+			for _, pair := range merged {
+				if !seen[pair[0].Value] {
+					seen[pair[0].Value] = true
+					pairs = append(pairs, pair)
+				}
+			}
+		}
+	}
+	return pairs, nil
+}
+
+// scalarToJSON writes the scalar as JSON, the numbers and booleans are normalized,
+// e.g. "0x1F", "1_000" and ".inf", other scalars (e.g. !!timestamp, !!binary) are
+// written as JSON strings which protojson parses by the field type.
+func scalarToJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.ShortTag() {
+	case "!!null":
+		buf.WriteString("null")
+		return nil
+	case "!!bool", "!!int", "!!float":
+		var v any
+		if err := node.Decode(&v); err != nil {
+			return err
+		}
+		if f, ok := v.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			switch {
+			case math.IsNaN(f):
+				v = "NaN"
+			case f > 0:
+				v = "Infinity"
+			default:
+				v = "-Infinity"
+			}
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		return nil
+	case "!!binary":
+		b, _ := json.Marshal(strings.Join(strings.Fields(node.Value), ""))
+		buf.Write(b)
+		return nil
+	default:
+		b, _ := json.Marshal(node.Value)
+		buf.Write(b)
+		return nil
+	}
+}
+
+var convFromType = map[reflect.Kind]reflect.Value{
+	reflect.String:  reflect.ValueOf(codec.String),
+	reflect.Bool:    reflect.ValueOf(codec.Bool),
+	reflect.Float64: reflect.ValueOf(codec.Float64),
+	reflect.Float32: reflect.ValueOf(codec.Float32),
+	reflect.Int64:   reflect.ValueOf(codec.Int64),
+	reflect.Int32:   reflect.ValueOf(codec.Int32),
+	reflect.Uint64:  reflect.ValueOf(codec.Uint64),
+	reflect.Uint32:  reflect.ValueOf(codec.Uint32),
+}
+
+var (
+	// protojson reports a field error like `proto: (line 1:8): invalid value for int64 field id: "abc"`.
+	protoInvalidValueRegexp = regexp.MustCompile(`invalid value for (\S+) field (\S+): (.*)$`)
+	// protojson reports an unknown field error like `proto: (line 1:2): unknown field "foo"`.
+	protoUnknownFieldRegexp = regexp.MustCompile(`unknown field "?([^"\s]+)"?$`)
+)
+
+// wrapProtoError converts the protojson decode failure of the message into *codec.FieldError.
+func wrapProtoError(err error, m proto.Message) error {
+	if err == nil {
+		return nil
+	}
+	fe := &codec.FieldError{Type: string(m.ProtoReflect().Descriptor().FullName()), Err: err}
+	msg := err.Error()
+	if r := protoInvalidValueRegexp.FindStringSubmatch(msg); r != nil {
+		value, e := strconv.Unquote(r[3])
+		if e != nil {
+			value = r[3]
+		}
+		fe.Field, fe.Value, fe.Type = r[2], value, r[1]
+	} else if r := protoUnknownFieldRegexp.FindStringSubmatch(msg); r != nil {
+		fe.Field = r[1]
+	}
+	return fe
+}
@@ -0,0 +1,178 @@
+package yaml
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/things-go/encoding/codec"
+	"github.com/things-go/encoding/testdata/examplepb"
+)
+
+var protoMessage = &examplepb.ABitOfEverything{
+	SingleNested:   &examplepb.ABitOfEverything_Nested{Name: "nested"},
+	Uuid:           "123",
+	Nested:         []*examplepb.ABitOfEverything_Nested{{Name: "foo", Amount: 12345}},
+	DoubleValue:    1.5,
+	Int64Value:     -1,
+	Uint64Value:    0xFFFFFFFFFFFFFFFF,
+	BoolValue:      true,
+	StringValue:    "true",
+	BytesValue:     []byte("bytes"),
+	EnumValue:      examplepb.NumericEnum_ONE,
+	MapValue:       map[string]examplepb.NumericEnum{"a": examplepb.NumericEnum_ONE},
+	TimestampValue: &timestamppb.Timestamp{Seconds: 1704164645},
+	OneofValue:     &examplepb.ABitOfEverything_OneofString{OneofString: "bar"},
+}
+
+func TestCodec_Proto(t *testing.T) {
+	m := &Codec{}
+	buf, err := m.Marshal(protoMessage)
+	if err != nil {
+		t.Fatalf("m.Marshal(%v) failed with %v; want success", protoMessage, err)
+	}
+	got := string(buf)
+	for _, want := range []string{
+		"singleNested:\n    name: nested\n",
+		"uuid: \"123\"\n",
+		"stringValue: \"true\"\n",
+		"enumValue: ONE\n",
+		"timestampValue: \"2024-01-02T03:04:05Z\"\n",
+		"oneofString: bar\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("m.Marshal(%v) = %q; want contains %q", protoMessage, got, want)
+		}
+	}
+	for _, unwanted := range []string{"state", "sizeCache", "unknownFields", "{", "["} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("m.Marshal(%v) = %q; want not contains %q", protoMessage, got, unwanted)
+		}
+	}
+
+	unmarshalled := &examplepb.ABitOfEverything{}
+	if err = m.Unmarshal(buf, unmarshalled); err != nil {
+		t.Fatalf("m.Unmarshal(%q) failed with %v; want success", buf, err)
+	}
+	if diff := cmp.Diff(unmarshalled, protoMessage, protocmp.Transform()); diff != "" {
+		t.Errorf("m.Unmarshal(%q) differs:\n%s", buf, diff)
+	}
+
+	var w bytes.Buffer
+	if err = m.NewEncoder(&w).Encode(protoMessage); err != nil {
+		t.Fatalf("Encode(%v) failed with %v; want success", protoMessage, err)
+	}
+	decoded := &examplepb.ABitOfEverything{}
+	if err = m.NewDecoder(&w).Decode(decoded); err != nil {
+		t.Fatalf("Decode() failed with %v; want success", err)
+	}
+	if diff := cmp.Diff(decoded, protoMessage, protocmp.Transform()); diff != "" {
+		t.Errorf("Decode() differs:\n%s", diff)
+	}
+}
+
+func TestCodec_ProtoAuthored(t *testing.T) {
+	data := `
+defaults: &defaults
+  amount: 0x10
+  ok: TRUE
+uuid: 6EC2446F
+single_nested:
+  <<: *defaults
+  name: nested
+nested:
+  - <<: *defaults
+    amount: 1_000
+doubleValue: .inf
+int64Value: 10
+timestampValue: 2024-01-02T03:04:05Z
+enumValue: 1
+`
+	want := &examplepb.ABitOfEverything{
+		Uuid:           "6EC2446F",
+		SingleNested:   &examplepb.ABitOfEverything_Nested{Name: "nested", Amount: 16, Ok: examplepb.ABitOfEverything_Nested_TRUE},
+		Nested:         []*examplepb.ABitOfEverything_Nested{{Amount: 1000, Ok: examplepb.ABitOfEverything_Nested_TRUE}},
+		Int64Value:     10,
+		TimestampValue: &timestamppb.Timestamp{Seconds: 1704164645},
+		EnumValue:      examplepb.NumericEnum_ONE,
+	}
+	m := &Codec{}
+	m.ProtoUnmarshalOptions.DiscardUnknown = true
+	got := &examplepb.ABitOfEverything{}
+	if err := m.Unmarshal([]byte(data), got); err != nil {
+		t.Fatalf("m.Unmarshal() failed with %v; want success", err)
+	}
+	if got.DoubleValue <= 1e308 {
+		t.Errorf("DoubleValue = %v; want +Inf", got.DoubleValue)
+	}
+	got.DoubleValue = 0
+	if diff := cmp.Diff(got, want, protocmp.Transform()); diff != "" {
+		t.Errorf("m.Unmarshal() differs:\n%s", diff)
+	}
+}
+
+func TestCodec_ProtoSlice(t *testing.T) {
+	m := &Codec{}
+	want := []*examplepb.SimpleMessage{{Id: "1"}, {Id: "2"}}
+	buf, err := m.Marshal(want)
+	if err != nil {
+		t.Fatalf("m.Marshal(%v) failed with %v; want success", want, err)
+	}
+	if got := string(buf); got != "- id: \"1\"\n- id: \"2\"\n" {
+		t.Errorf("m.Marshal(%v) = %q", want, got)
+	}
+	var got []*examplepb.SimpleMessage
+	if err = m.Unmarshal(buf, &got); err != nil {
+		t.Fatalf("m.Unmarshal(%q) failed with %v; want success", buf, err)
+	}
+	if diff := cmp.Diff(got, want, protocmp.Transform()); diff != "" {
+		t.Errorf("m.Unmarshal(%q) differs:\n%s", buf, diff)
+	}
+
+	wantMap := map[string]*examplepb.SimpleMessage{"b": {Id: "2"}, "a": {Id: "1"}, "1": {Id: "3"}}
+	buf, err = m.Marshal(wantMap)
+	if err != nil {
+		t.Fatalf("m.Marshal(%v) failed with %v; want success", wantMap, err)
+	}
+	if got := string(buf); got != "\"1\":\n    id: \"3\"\na:\n    id: \"1\"\nb:\n    id: \"2\"\n" {
+		t.Errorf("m.Marshal(%v) = %q", wantMap, got)
+	}
+	gotMap := map[string]*examplepb.SimpleMessage{}
+	if err = m.Unmarshal(buf, &gotMap); err != nil {
+		t.Fatalf("m.Unmarshal(%q) failed with %v; want success", buf, err)
+	}
+	if diff := cmp.Diff(gotMap, wantMap, protocmp.Transform()); diff != "" {
+		t.Errorf("m.Unmarshal(%q) differs:\n%s", buf, diff)
+	}
+}
+
+func TestCodec_ProtoFieldError(t *testing.T) {
+	m := &Codec{}
+	tests := []struct {
+		name      string
+		data      string
+		v         any
+		wantField string
+	}{
+		{"invalid value", "int64Value: abc\n", &examplepb.ABitOfEverything{}, "int64Value"},
+		{"unknown field", "timout: 5s\n", &examplepb.ABitOfEverything{}, "timout"},
+		{"element", "- id: 1\n- foo: 2\n", &[]*examplepb.SimpleMessage{}, "[1].foo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fe *codec.FieldError
+			err := m.Unmarshal([]byte(tt.data), tt.v)
+			if !errors.As(err, &fe) {
+				t.Fatalf("error = %v, want *codec.FieldError", err)
+			}
+			if fe.Field != tt.wantField {
+				t.Errorf("FieldError.Field = %q, want %q", fe.Field, tt.wantField)
+			}
+		})
+	}
+}
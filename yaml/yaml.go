@@ -1,29 +1,157 @@
 package yaml
 
 import (
+	"errors"
 	"io"
+	"regexp"
+	"strings"
 
+	"google.golang.org/protobuf/encoding/protojson"
 	"gopkg.in/yaml.v3"
 
 	"github.com/things-go/encoding/codec"
 )
 
 // Codec is a Codec implementation with yaml.
-type Codec struct{}
+// The proto messages are encoded with the protojson semantics as the idiomatic YAML,
+// so the configs can be defined in proto but authored in YAML.
+// The NewEncoder returns an EncoderWrapper of *yaml.Encoder,
+// and the NewDecoder returns a DecoderWrapper of *yaml.Decoder.
+// The decode failures are reported as *codec.FieldError or codec.FieldErrors, whose Field is
+// empty since yaml.v3 does not report the fields, except the unknown fields of KnownFields and
+// the failures of the proto messages.
+type Codec struct {
+	// KnownFields reports the keys which match no struct fields as codec.FieldErrors,
+	// with the dotted field paths and the line numbers, instead of ignoring them.
+	KnownFields bool
+	// ProtoMarshalOptions marshals the proto messages, e.g. UseProtoNames, UseEnumNumbers.
+	ProtoMarshalOptions protojson.MarshalOptions
+	// ProtoUnmarshalOptions unmarshals the proto messages, e.g. DiscardUnknown, Resolver.
+	ProtoUnmarshalOptions protojson.UnmarshalOptions
+}
 
 // ContentType always Returns "application/x-yaml; charset=utf-8".
 func (*Codec) ContentType(_ any) string {
 	return "application/x-yaml; charset=utf-8"
 }
-func (*Codec) Marshal(v any) ([]byte, error) {
+func (c *Codec) Marshal(v any) ([]byte, error) {
+	if isProtoTarget(v) {
+		node, err := protoToNode(c.ProtoMarshalOptions, v)
+		if err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(node)
+	}
 	return yaml.Marshal(v)
 }
-func (*Codec) Unmarshal(data []byte, v any) error {
-	return yaml.Unmarshal(data, v)
+func (c *Codec) Unmarshal(data []byte, v any) error {
+	if isProtoTarget(v) {
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return wrapError(err)
+		}
+		return decodeProtoNode(c.ProtoUnmarshalOptions, &node, v)
+	}
+	if c.KnownFields {
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return wrapError(err)
+		}
+		return decodeKnownFields(&node, v)
+	}
+	return wrapError(yaml.Unmarshal(data, v))
+}
+func (c *Codec) NewEncoder(w io.Writer) codec.Encoder {
+	return EncoderWrapper{
+		Encoder:             yaml.NewEncoder(w),
+		ProtoMarshalOptions: c.ProtoMarshalOptions,
+	}
+}
+func (c *Codec) NewDecoder(r io.Reader) codec.Decoder {
+	return DecoderWrapper{
+		Decoder:               yaml.NewDecoder(r),
+		ProtoUnmarshalOptions: c.ProtoUnmarshalOptions,
+		knownFields:           c.KnownFields,
+	}
+}
+
+// EncoderWrapper is a wrapper around a *yaml.Encoder that adds
+// support for protos to the Encode method.
+type EncoderWrapper struct {
+	*yaml.Encoder
+	ProtoMarshalOptions protojson.MarshalOptions
+}
+
+// Encode wraps the embedded encoder's Encode method to support protos.
+func (e EncoderWrapper) Encode(v any) error {
+	if isProtoTarget(v) {
+		node, err := protoToNode(e.ProtoMarshalOptions, v)
+		if err != nil {
+			return err
+		}
+		return e.Encoder.Encode(node)
+	}
+	return e.Encoder.Encode(v)
+}
+
+// DecoderWrapper is a wrapper around a *yaml.Decoder that adds
+// support for protos to the Decode method, and reports
+// the decode failures as *codec.FieldError or codec.FieldErrors.
+type DecoderWrapper struct {
+	*yaml.Decoder
+	ProtoUnmarshalOptions protojson.UnmarshalOptions
+	// knownFields reports the unknown fields as codec.FieldErrors, see Codec.KnownFields.
+	knownFields bool
 }
-func (*Codec) NewEncoder(w io.Writer) codec.Encoder {
-	return yaml.NewEncoder(w)
+
+// Decode wraps the embedded decoder's Decode method.
+func (d DecoderWrapper) Decode(v any) error {
+	if isProtoTarget(v) {
+		var node yaml.Node
+		if err := d.Decoder.Decode(&node); err != nil {
+			return wrapError(err)
+		}
+		return decodeProtoNode(d.ProtoUnmarshalOptions, &node, v)
+	}
+	if d.knownFields {
+		var node yaml.Node
+		if err := d.Decoder.Decode(&node); err != nil {
+			return wrapError(err)
+		}
+		return decodeKnownFields(&node, v)
+	}
+	return wrapError(d.Decoder.Decode(v))
 }
-func (*Codec) NewDecoder(r io.Reader) codec.Decoder {
-	return yaml.NewDecoder(r)
+
+// yaml reports a type error like "line 1: cannot unmarshal !!str `abc` into int".
+var typeErrorRegexp = regexp.MustCompile("cannot unmarshal !!\\w+ `(.*)` into (.+)$")
+
+// wrapError converts the decode failures into *codec.FieldError or codec.FieldErrors,
+// other errors (e.g. the reader's error) are returned as is.
+// NOTE: *yaml.TypeError reports all the mismatched values, but not the fields.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		errs := make(codec.FieldErrors, 0, len(typeErr.Errors))
+		for _, e := range typeErr.Errors {
+			errs = append(errs, typeFieldError(e))
+		}
+		return errs.Err()
+	}
+	if strings.HasPrefix(err.Error(), "yaml:") {
+		return &codec.FieldError{Err: err}
+	}
+	return err
+}
+
+// typeFieldError converts an error of *yaml.TypeError into *codec.FieldError.
+func typeFieldError(e string) *codec.FieldError {
+	fe := &codec.FieldError{Err: errors.New(e)}
+	if m := typeErrorRegexp.FindStringSubmatch(e); m != nil {
+		fe.Value, fe.Type = m[1], m[2]
+	}
+	return fe
 }
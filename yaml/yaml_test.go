@@ -2,9 +2,16 @@ package yaml
 
 import (
 	"bytes"
+	"errors"
 	"math"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/things-go/encoding/codec"
 )
 
 func TestCodec_ContentType(t *testing.T) {
@@ -156,3 +163,147 @@ var unmarshalerTests = []struct {
 		map[string]any{"v": -0.1},
 	},
 }
+
+func TestCodec_DecodeFieldError(t *testing.T) {
+	type value struct {
+		N int `yaml:"n"`
+		M int `yaml:"m"`
+	}
+	m := Codec{}
+	data := "n: abc\nm: def\n"
+	for _, err := range []error{
+		m.Unmarshal([]byte(data), &value{}),
+		m.NewDecoder(strings.NewReader(data)).Decode(&value{}),
+	} {
+		var errs codec.FieldErrors
+		if !errors.As(err, &errs) {
+			t.Fatalf("error = %v, want codec.FieldErrors", err)
+		}
+		if len(errs) != 2 || errs[0].Value != "abc" || errs[1].Value != "def" || errs[0].Type != "int" {
+			t.Errorf("FieldErrors = %v, want all values collected", errs)
+		}
+	}
+
+	var fe *codec.FieldError
+	if err := m.Unmarshal([]byte("n: [abc\n"), &value{}); !errors.As(err, &fe) {
+		t.Errorf("error = %v, want *codec.FieldError", err)
+	}
+}
+
+// legacyUnmarshaler implements the obsolete yaml.v2 Unmarshaler, which accepts any keys.
+type legacyUnmarshaler map[string]any
+
+func (l *legacyUnmarshaler) UnmarshalYAML(unmarshal func(any) error) error {
+	m := map[string]any{}
+	if err := unmarshal(&m); err != nil {
+		return err
+	}
+	*l = m
+	return nil
+}
+
+func TestCodec_KnownFields(t *testing.T) {
+	type server struct {
+		Host    string        `yaml:"host"`
+		Timeout time.Duration `yaml:"timeout"`
+	}
+	type base struct {
+		Name string `yaml:"name"`
+	}
+	type flow struct {
+		A struct {
+			X int `yaml:"x"`
+		} `yaml:"a"`
+		B struct {
+			Y int `yaml:"y"`
+		} `yaml:"b"`
+	}
+	type config struct {
+		base    `yaml:",inline"`
+		Count   int               `yaml:"count"`
+		Flow    flow              `yaml:"flow"`
+		Servers []server          `yaml:"servers"`
+		Main    *server           `yaml:"main"`
+		Labels  map[string]server `yaml:"labels"`
+		Any     any               `yaml:"any"`
+		Node    yaml.Node         `yaml:"node"`
+		Legacy  legacyUnmarshaler `yaml:"legacy"`
+	}
+	data := strings.Join([]string{
+		"name: foo",
+		"defaults: &defaults",
+		"  host: localhost",
+		"servers:",
+		"  - <<: *defaults",
+		"    timout: 5s",
+		"main:",
+		"  host: a",
+		"labels:",
+		"  x:",
+		"    hots: b",
+		"any:",
+		"  whatever: 1",
+		"node:",
+		"  whatever: 1",
+		"legacy:",
+		"  whatever: 1",
+		"flow: {a: {x: 1}, b: {x: 2}}",
+		"",
+	}, "\n")
+	want := []struct {
+		field string
+		line  string
+	}{
+		{"defaults", "line 2:"},
+		{"servers[0].timout", "line 6:"},
+		{"labels.x.hots", "line 11:"},
+		{"flow.b.x", "line 18:"},
+	}
+
+	m := Codec{KnownFields: true}
+	for _, err := range []error{
+		m.Unmarshal([]byte(data), &config{}),
+		m.NewDecoder(strings.NewReader(data)).Decode(&config{}),
+	} {
+		var errs codec.FieldErrors
+		if !errors.As(err, &errs) {
+			t.Fatalf("error = %v, want codec.FieldErrors", err)
+		}
+		if len(errs) != len(want) {
+			t.Fatalf("FieldErrors = %v, want %d errors", errs, len(want))
+		}
+		for i, w := range want {
+			if errs[i].Field != w.field || !strings.HasPrefix(errs[i].Err.Error(), w.line) {
+				t.Errorf("FieldErrors[%d] = %v, want the field %q at %q", i, errs[i], w.field, w.line)
+			}
+		}
+	}
+
+	t.Run("known", func(t *testing.T) {
+		data := "name: foo\nmain:\n  host: a\n  timeout: 5s\n"
+		var got config
+		if err := m.Unmarshal([]byte(data), &got); err != nil {
+			t.Fatalf("Unmarshal() failed with %v; want success", err)
+		}
+		if got.Name != "foo" || got.Main == nil || got.Main.Timeout != 5*time.Second {
+			t.Errorf("Unmarshal() = %+v; want the fields", got)
+		}
+		if err := m.Unmarshal(nil, &got); err != nil {
+			t.Errorf("Unmarshal(nil) failed with %v; want success", err)
+		}
+	})
+	t.Run("type errors", func(t *testing.T) {
+		var errs codec.FieldErrors
+		if err := m.Unmarshal([]byte("count: abc\nfoo: 1\n"), &config{}); !errors.As(err, &errs) {
+			t.Fatalf("error = %v, want codec.FieldErrors", err)
+		}
+		if len(errs) != 2 || errs[0].Value != "abc" || errs[1].Field != "foo" {
+			t.Errorf("FieldErrors = %v, want the type error then the unknown field in line order", errs)
+		}
+	})
+	t.Run("unknown allowed", func(t *testing.T) {
+		if err := (&Codec{}).Unmarshal([]byte(data), &config{}); err != nil {
+			t.Errorf("Unmarshal() failed with %v; want success", err)
+		}
+	})
+}